@@ -0,0 +1,245 @@
+package app
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/RiskyFeryansyahP/paycast/internal/completion"
+	"github.com/RiskyFeryansyahP/paycast/internal/store"
+	"github.com/RiskyFeryansyahP/paycast/internal/supervisor"
+	"github.com/RiskyFeryansyahP/paycast/pkg/dryrun"
+	"github.com/RiskyFeryansyahP/paycast/pkg/exit"
+	"github.com/RiskyFeryansyahP/paycast/pkg/logger"
+	"github.com/RiskyFeryansyahP/paycast/pkg/output"
+	"github.com/spf13/cobra"
+)
+
+var appCmd = &cobra.Command{
+	GroupID: "basic",
+	Use:     "app",
+	Short:   "Manage application proxy configurations",
+	Long:    "Add, remove, and run Teleport application proxies for the current context",
+}
+
+var appRunCmd = &cobra.Command{
+	Use:   "run",
+	Short: "Start all configured application proxies",
+	Long:  "Start application proxy connections for all applications configured in the current context",
+	Args:  cobra.NoArgs,
+	RunE:  appRun,
+}
+
+var appAddCmd = &cobra.Command{
+	Use:   "add",
+	Short: "Add a new application proxy configuration",
+	Long:  "Configure a new application proxy to be managed by paycast",
+	Args:  cobra.NoArgs,
+	RunE:  appAddRun,
+}
+
+var appListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List application proxy configurations",
+	Long:  "List the applications configured in the current context",
+	Args:  cobra.NoArgs,
+	RunE:  appListRun,
+}
+
+var appDeleteCmd = &cobra.Command{
+	Use:               "delete <name>",
+	Short:             "Remove an application proxy configuration",
+	Long:              "Delete the specified application configuration from the current context",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completion.First(completion.Apps),
+	RunE:              appDeleteRun,
+}
+
+func NewAppCommand() *cobra.Command {
+	var name, cluster string
+	var port int32
+
+	appAddCmd.Flags().StringVarP(&name, "name", "n", "", "Name of the Teleport application")
+	appAddCmd.Flags().StringVarP(&cluster, "cluster", "c", "", "Leaf cluster the application is reached through, defaults to the root cluster")
+	appAddCmd.Flags().Int32VarP(&port, "port", "p", 0, "Specifies the source port used by proxy app listener")
+	_ = appAddCmd.RegisterFlagCompletionFunc("cluster", completion.LeafClusters)
+	_ = appAddCmd.MarkFlagRequired("name")
+	_ = appAddCmd.MarkFlagRequired("port")
+
+	appCmd.AddCommand(appAddCmd, appDeleteCmd, appListCmd, appRunCmd)
+
+	return appCmd
+}
+
+func appAddRun(cobraCmd *cobra.Command, args []string) error {
+	ctx := cobraCmd.Context()
+
+	name := cobraCmd.Flag("name").Value.String()
+	cluster := cobraCmd.Flag("cluster").Value.String()
+	port, _ := cobraCmd.Flags().GetInt32("port")
+
+	config, currentContext, err := store.LoadCurrent(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	configContext := config.Contexts[currentContext]
+
+	cluster, err = configContext.LeafCluster(cluster)
+
+	if err != nil {
+		return err
+	}
+
+	owner, taken := configContext.PortOwner(port)
+
+	if taken && owner != "app/"+name {
+		return exit.New(exit.PortInUse,
+			fmt.Errorf("port %d is already used by %s", port, owner),
+			"Choose a different port")
+	}
+
+	if len(configContext.Apps) == 0 {
+		configContext.Apps = make(map[string]store.App)
+	}
+
+	configContext.Apps[name] = store.App{
+		Name:    name,
+		Port:    port,
+		Cluster: cluster,
+	}
+	config.Contexts[currentContext] = configContext
+
+	err = store.Save(ctx, config)
+
+	if err != nil {
+		return fmt.Errorf("failed to save configuration file: %w", err)
+	}
+
+	logger.Info().
+		Str("app", name).
+		Int("port", int(port)).
+		Msg(dryrun.Message("Application added successfully", "Dry run, application would be added"))
+
+	return nil
+}
+
+func appDeleteRun(cobraCmd *cobra.Command, args []string) error {
+	ctx := cobraCmd.Context()
+
+	config, currentContext, err := store.LoadCurrent(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	configContext := config.Contexts[currentContext]
+
+	name := args[0]
+
+	_, ok := configContext.Apps[name]
+
+	if !ok {
+		return exit.New(exit.Failure,
+			fmt.Errorf("application '%s' not found", name),
+			"Run 'paycast app list' to see configured applications")
+	}
+
+	if workspace, ok := configContext.WorkspaceUsing("app/" + name); ok {
+		return exit.New(exit.Failure,
+			fmt.Errorf("application '%s' is used by workspace '%s'", name, workspace),
+			"Update the workspace with 'paycast workspace create' or delete it first")
+	}
+
+	delete(configContext.Apps, name)
+	config.Contexts[currentContext] = configContext
+
+	err = store.Save(ctx, config)
+
+	if err != nil {
+		return fmt.Errorf("failed to save configuration file: %w", err)
+	}
+
+	logger.Info().
+		Str("app", name).
+		Msg(dryrun.Message("Application deleted successfully", "Dry run, application would be deleted"))
+
+	return nil
+}
+
+func appListRun(cobraCmd *cobra.Command, args []string) error {
+	config, currentContext, err := store.LoadCurrent(cobraCmd.Context())
+
+	if err != nil {
+		return err
+	}
+
+	configContext := config.Contexts[currentContext]
+
+	names := make([]string, 0, len(configContext.Apps))
+
+	for name := range configContext.Apps {
+		names = append(names, name)
+	}
+
+	slices.Sort(names)
+
+	result := make(AppsResult, 0, len(names))
+
+	for _, name := range names {
+		result = append(result, newAppEntry(configContext.Apps[name]))
+	}
+
+	err = output.Print(result)
+
+	if err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+
+	return nil
+}
+
+func appRun(cobraCmd *cobra.Command, args []string) error {
+	ctx := cobraCmd.Context()
+
+	config, currentContext, err := store.LoadCurrent(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	configContext := config.Contexts[currentContext]
+
+	proxies := make([]supervisor.Proxy, 0, len(configContext.Apps))
+
+	for _, app := range configContext.Apps {
+		proxies = append(proxies, Proxy(app))
+	}
+
+	err = supervisor.Run(ctx, config, currentContext, proxies)
+
+	if err != nil {
+		return fmt.Errorf("failed to run application proxies: %w", err)
+	}
+
+	return nil
+}
+
+// Proxy describes the tsh proxy serving an application
+func Proxy(app store.App) supervisor.Proxy {
+	args := []string{"proxy", "app", app.Name, fmt.Sprintf("--port=%d", app.Port)}
+
+	if app.Cluster != "" {
+		args = append(args, fmt.Sprintf("--cluster=%s", app.Cluster))
+	}
+
+	return supervisor.Proxy{
+		Kind:  "app",
+		Name:  app.Name,
+		Ports: []int32{app.Port},
+		Args:  args,
+		Fields: map[string]string{
+			"url": fmt.Sprintf("http://localhost:%d", app.Port),
+		},
+	}
+}
@@ -0,0 +1,39 @@
+package app
+
+import (
+	"strconv"
+
+	"github.com/RiskyFeryansyahP/paycast/internal/store"
+)
+
+// AppsResult is the output of 'app list'
+type AppsResult []AppEntry
+
+// AppEntry is an application of the context served on a local port
+type AppEntry struct {
+	Name    string `json:"name"`
+	Port    int32  `json:"port"`
+	Cluster string `json:"cluster,omitempty"`
+}
+
+func newAppEntry(app store.App) AppEntry {
+	return AppEntry{
+		Name:    app.Name,
+		Port:    app.Port,
+		Cluster: app.Cluster,
+	}
+}
+
+func (r AppsResult) Header() []string {
+	return []string{"NAME", "PORT", "CLUSTER"}
+}
+
+func (r AppsResult) Rows() [][]string {
+	rows := make([][]string, 0, len(r))
+
+	for _, app := range r {
+		rows = append(rows, []string{app.Name, strconv.Itoa(int(app.Port)), app.Cluster})
+	}
+
+	return rows
+}
@@ -0,0 +1,197 @@
+package auth
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/RiskyFeryansyahP/paycast/internal/completion"
+	"github.com/RiskyFeryansyahP/paycast/internal/store"
+	"github.com/RiskyFeryansyahP/paycast/pkg/cmd"
+	"github.com/RiskyFeryansyahP/paycast/pkg/dryrun"
+	"github.com/RiskyFeryansyahP/paycast/pkg/exit"
+	"github.com/RiskyFeryansyahP/paycast/pkg/logger"
+	"github.com/spf13/cobra"
+)
+
+var loginCmd = &cobra.Command{
+	GroupID:           "basic",
+	Use:               "login [context]",
+	Short:             "Log in to a context again",
+	Long:              "Reauthenticate to Teleport with the stored parameters of a context, defaults to the current context",
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: completion.First(completion.Contexts),
+	RunE:              loginRun,
+}
+
+var logoutCmd = &cobra.Command{
+	GroupID:           "basic",
+	Use:               "logout [context]",
+	Short:             "Log out of a context",
+	Long:              "Remove the Teleport session of a context and stop its running proxies, defaults to the current context",
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: completion.First(completion.Contexts),
+	RunE:              logoutRun,
+}
+
+func NewLoginCommand() *cobra.Command {
+	return loginCmd
+}
+
+func NewLogoutCommand() *cobra.Command {
+	var all bool
+
+	logoutCmd.Flags().BoolVar(&all, "all", false, "Log out of every context")
+
+	return logoutCmd
+}
+
+func loginRun(cobraCmd *cobra.Command, args []string) error {
+	ctx := cobraCmd.Context()
+
+	config, err := store.Load(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	contextName, err := contextArg(config, args)
+
+	if err != nil {
+		return err
+	}
+
+	configContext, ok := config.Contexts[contextName]
+
+	if !ok {
+		return exit.New(exit.ContextMissing,
+			fmt.Errorf("context '%s' not found", contextName),
+			"Run 'paycast config set-context' to see available contexts")
+	}
+
+	updatedConfigContext, err := cmd.Relogin(ctx, &configContext)
+
+	if err != nil {
+		return fmt.Errorf("failed to login to context '%s': %w", contextName, err)
+	}
+
+	config.Contexts[contextName] = *updatedConfigContext
+
+	err = store.Save(ctx, config)
+
+	if err != nil {
+		return fmt.Errorf("failed to save configuration file: %w", err)
+	}
+
+	logger.Info().
+		Str("context", contextName).
+		Str("cluster", updatedConfigContext.Cluster).
+		Time("expiry", updatedConfigContext.Expiry.Local()).
+		Msg(dryrun.Message("Logged in successfully", "Dry run, would log in"))
+
+	return nil
+}
+
+func logoutRun(cobraCmd *cobra.Command, args []string) error {
+	ctx := cobraCmd.Context()
+
+	all, _ := cobraCmd.Flags().GetBool("all")
+
+	if all && len(args) > 0 {
+		return exit.New(exit.Usage, fmt.Errorf("--all cannot be combined with a context name"), "")
+	}
+
+	config, err := store.Load(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	var contextNames []string
+
+	// --all needs no current context, only a single logout resolves it
+	if all {
+		for name := range config.Contexts {
+			contextNames = append(contextNames, name)
+		}
+	} else {
+		contextName, err := contextArg(config, args)
+
+		if err != nil {
+			return err
+		}
+
+		contextNames = []string{contextName}
+	}
+
+	for _, contextName := range contextNames {
+		configContext, ok := config.Contexts[contextName]
+
+		if !ok {
+			return exit.New(exit.ContextMissing,
+				fmt.Errorf("context '%s' not found", contextName),
+				"Run 'paycast config set-context' to see available contexts")
+		}
+
+		store.StopProcesses(contextName)
+
+		logoutArgs := []string{"logout"}
+
+		if configContext.Proxy != "" {
+			logoutArgs = append(logoutArgs, fmt.Sprintf("--proxy=%s", configContext.Proxy))
+		}
+
+		if configContext.User != "" {
+			logoutArgs = append(logoutArgs, fmt.Sprintf("--user=%s", configContext.User))
+		}
+
+		output, err := cmd.Command(ctx, configContext.TeleportHome(), logoutArgs...).CombinedOutput()
+
+		if err != nil {
+			logger.Warn().
+				Err(err).
+				Str("context", contextName).
+				Str("output", string(output)).
+				Msg("tsh logout failed, the session may already be gone")
+		}
+
+		if !dryrun.Enabled() {
+			err = os.Remove(configContext.Kubeconfig())
+
+			if err != nil && !os.IsNotExist(err) {
+				logger.Warn().
+					Err(err).
+					Str("context", contextName).
+					Msg("Failed to remove context kubeconfig")
+			}
+		}
+
+		configContext.Expiry = time.Time{}
+		config.Contexts[contextName] = configContext
+
+		logger.Info().
+			Str("context", contextName).
+			Msg(dryrun.Message("Logged out successfully", "Dry run, would log out"))
+	}
+
+	err = store.Save(ctx, config)
+
+	if err != nil {
+		return fmt.Errorf("failed to save configuration file: %w", err)
+	}
+
+	return nil
+}
+
+// contextArg returns the context named on the command line, or the current one
+func contextArg(config store.Config, args []string) (string, error) {
+	if len(args) > 0 {
+		return args[0], nil
+	}
+
+	if config.Active() == "" {
+		return "", store.ErrNoContext
+	}
+
+	return config.Active(), nil
+}
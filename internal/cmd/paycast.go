@@ -1,11 +1,24 @@
 package cmd
 
 import (
-	"fmt"
 	"runtime/debug"
+	"strings"
 
+	"github.com/RiskyFeryansyahP/paycast/internal/app"
+	"github.com/RiskyFeryansyahP/paycast/internal/auth"
 	"github.com/RiskyFeryansyahP/paycast/internal/config"
 	"github.com/RiskyFeryansyahP/paycast/internal/database"
+	"github.com/RiskyFeryansyahP/paycast/internal/kube"
+	"github.com/RiskyFeryansyahP/paycast/internal/prompt"
+	"github.com/RiskyFeryansyahP/paycast/internal/request"
+	"github.com/RiskyFeryansyahP/paycast/internal/shell"
+	"github.com/RiskyFeryansyahP/paycast/internal/ssh"
+	"github.com/RiskyFeryansyahP/paycast/internal/status"
+	"github.com/RiskyFeryansyahP/paycast/internal/workspace"
+	"github.com/RiskyFeryansyahP/paycast/pkg/dryrun"
+	"github.com/RiskyFeryansyahP/paycast/pkg/exit"
+	"github.com/RiskyFeryansyahP/paycast/pkg/logger"
+	"github.com/RiskyFeryansyahP/paycast/pkg/output"
 	"github.com/spf13/cobra"
 )
 
@@ -13,38 +26,148 @@ var (
 	version = "dev"
 
 	rootCmd = &cobra.Command{
-		Use:   "paycast",
-		Short: "Internal CLI tool for managing contexts and workflows",
-		Long:  "Paycast helps manage authentication contexts, database proxies, and other development tools",
+		Use:               "paycast",
+		Short:             "Internal CLI tool for managing contexts and workflows",
+		Long:              "Paycast helps manage authentication contexts, database proxies, and other development tools",
+		PersistentPreRunE: applyGlobalFlags,
 	}
 
 	versionCmd = &cobra.Command{
 		Use:   "version",
 		Short: "Print version the CLI installed",
-		Run: func(cmd *cobra.Command, args []string) {
+		RunE: func(cmd *cobra.Command, args []string) error {
+			result := VersionResult{Version: version}
+
 			info, ok := debug.ReadBuildInfo()
 
 			if ok && info.Main.Version != "" {
-				fmt.Println(info.Main.Version)
-				return
+				result.Version = info.Main.Version
 			}
 
-			fmt.Println(version)
+			return output.Print(result)
 		},
 	}
 )
 
+// VersionResult is the output of 'paycast version'
+type VersionResult struct {
+	Version string `json:"version"`
+}
+
+func (r VersionResult) Header() []string {
+	return []string{"VERSION"}
+}
+
+func (r VersionResult) Rows() [][]string {
+	return [][]string{{r.Version}}
+}
+
+func (r VersionResult) Text() string {
+	return r.Version
+}
+
+// applyGlobalFlags applies the global flags before any command runs. Logs go
+// to stderr so stdout only carries command results
+func applyGlobalFlags(cobraCmd *cobra.Command, args []string) error {
+	verbose, _ := cobraCmd.Flags().GetCount("verbose")
+
+	err := logger.Configure(logger.Options{
+		Level:   cobraCmd.Flag("log-level").Value.String(),
+		Verbose: verbose,
+		Format:  cobraCmd.Flag("log-format").Value.String(),
+		File:    cobraCmd.Flag("log-file").Value.String(),
+	})
+
+	if err != nil {
+		return exit.New(exit.Usage, err, "")
+	}
+
+	dryRun, _ := cobraCmd.Flags().GetBool("dry-run")
+	dryrun.Set(dryRun)
+
+	format, err := output.Parse(cobraCmd.Flag("output").Value.String())
+
+	if err != nil {
+		return exit.New(exit.Usage, err, "")
+	}
+
+	output.Set(format)
+
+	return nil
+}
+
 func init() {
-	rootCmd.CompletionOptions.DisableDefaultCmd = true
+	var outputFormat, logLevel, logFormat, logFile string
+	var dryRun bool
+	var verbose int
+
+	// Errors are rendered by Execute, a failing command is not a usage mistake
+	rootCmd.SilenceErrors = true
+	rootCmd.SilenceUsage = true
+	rootCmd.SetFlagErrorFunc(func(cobraCmd *cobra.Command, err error) error {
+		return exit.New(exit.Usage, err, "Run '"+cobraCmd.CommandPath()+" --help' for usage")
+	})
+
+	rootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", string(output.Text), "Output format, one of "+strings.Join(output.Formats, ", "))
+	rootCmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "Show the tsh commands and configuration changes without running or saving them")
+	rootCmd.PersistentFlags().CountVarP(&verbose, "verbose", "v", "Log debug messages, repeat for trace messages")
+	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", "", "Minimum log level, e.g. debug or warn, defaults to LOG_LEVEL or info")
+	rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", logger.FormatConsole, "Log format, one of "+strings.Join(logger.Formats, ", "))
+	rootCmd.PersistentFlags().StringVar(&logFile, "log-file", "", "Also append logs to this file")
+	_ = rootCmd.RegisterFlagCompletionFunc("output", cobra.FixedCompletions(output.Formats, cobra.ShellCompDirectiveNoFileComp))
+	_ = rootCmd.RegisterFlagCompletionFunc("log-format", cobra.FixedCompletions(logger.Formats, cobra.ShellCompDirectiveNoFileComp))
+	_ = rootCmd.RegisterFlagCompletionFunc("log-level", cobra.FixedCompletions(
+		[]string{"trace", "debug", "info", "warn", "error"}, cobra.ShellCompDirectiveNoFileComp))
 
 	configCmd := config.NewConfigCommand()
 	dbCmd := database.NewConfigCommand()
+	appCmd := app.NewAppCommand()
+	sshCmd := ssh.NewSSHCommand()
+	workspaceCmd := workspace.NewWorkspaceCommand()
+	upCmd := workspace.NewUpCommand()
+	downCmd := workspace.NewDownCommand()
+	loginCmd := auth.NewLoginCommand()
+	logoutCmd := auth.NewLogoutCommand()
+	statusCmd := status.NewStatusCommand()
+	requestCmd := request.NewRequestCommand()
+	kubeCmd := kube.NewKubeCommand()
+	shellCmd := shell.NewShellCommand()
+	promptCmd := prompt.NewPromptCommand()
 
 	rootCmd.AddGroup(&cobra.Group{ID: "basic", Title: "Basic Commands:"})
 	rootCmd.AddCommand(versionCmd)
-	rootCmd.AddCommand(configCmd, dbCmd)
+	rootCmd.AddCommand(configCmd, dbCmd, appCmd, sshCmd)
+	rootCmd.AddCommand(workspaceCmd, upCmd, downCmd)
+	rootCmd.AddCommand(loginCmd, logoutCmd, statusCmd, requestCmd, kubeCmd)
+	rootCmd.AddCommand(shellCmd, promptCmd)
 }
 
-func Execute() error {
-	return rootCmd.Execute()
+// Execute runs the command line and returns the exit code of the process.
+// Errors of commands are rendered here, once, together with their hint, and
+// buffered logs are flushed before returning
+func Execute() int {
+	defer logger.Close()
+
+	err := rootCmd.Execute()
+
+	if err == nil {
+		return int(exit.OK)
+	}
+
+	if exit.IsSilent(err) {
+		return int(exit.CodeOf(err))
+	}
+
+	message := exit.HintOf(err)
+
+	if message == "" {
+		message = "Command failed"
+	}
+
+	logger.Error().
+		Err(err).
+		Int("code", int(exit.CodeOf(err))).
+		Msg(message)
+
+	return int(exit.CodeOf(err))
 }
@@ -0,0 +1,146 @@
+package completion
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/RiskyFeryansyahP/paycast/internal/store"
+	"github.com/spf13/cobra"
+)
+
+// Completions only read the paycast configuration, they never run tsh so
+// completing stays fast and works offline
+
+// Contexts completes the names of the configured contexts
+func Contexts(cobraCmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	config, ok := load(cobraCmd)
+
+	if !ok {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	return complete(keys(config.Contexts), args, toComplete)
+}
+
+// Databases completes the database tunnels of the active context
+var Databases = fromContext(func(c store.Context) []string {
+	return keys(c.Database)
+})
+
+// Apps completes the applications of the active context
+var Apps = fromContext(func(c store.Context) []string {
+	return keys(c.Apps)
+})
+
+// Hosts completes the SSH host aliases of the active context
+var Hosts = fromContext(func(c store.Context) []string {
+	return keys(c.Hosts)
+})
+
+// Workspaces completes the workspaces of the active context
+var Workspaces = fromContext(func(c store.Context) []string {
+	return keys(c.Workspaces)
+})
+
+// LeafClusters completes the leaf clusters of the active context
+var LeafClusters = fromContext(func(c store.Context) []string {
+	return c.LeafClusters
+})
+
+// KubeClusters completes the Kubernetes clusters logged in with
+// 'paycast kube login'
+var KubeClusters = fromContext(func(c store.Context) []string {
+	return c.KubeClusters
+})
+
+// PendingRequests completes the access requests waiting for review
+var PendingRequests = fromContext(func(c store.Context) []string {
+	return c.PendingRequests
+})
+
+// DBUsers completes the database users the session allows
+var DBUsers = fromSession(func(c store.Context) []string {
+	return c.Traits["db_users"]
+})
+
+// DBNames completes the database names the session allows
+var DBNames = fromSession(func(c store.Context) []string {
+	return c.Traits["db_names"]
+})
+
+// Logins completes the SSH logins the session allows
+var Logins = fromSession(func(c store.Context) []string {
+	return c.Logins
+})
+
+// First only completes the first positional argument with fn
+func First(fn cobra.CompletionFunc) cobra.CompletionFunc {
+	return func(cobraCmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		return fn(cobraCmd, args, toComplete)
+	}
+}
+
+// fromContext completes the names returned by fn for the active context
+func fromContext(fn func(store.Context) []string) cobra.CompletionFunc {
+	return func(cobraCmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		config, ok := load(cobraCmd)
+
+		if !ok {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		return complete(fn(config.Contexts[config.Active()]), args, toComplete)
+	}
+}
+
+// fromSession is fromContext for names recorded at login, offered only while
+// the session of the active context is valid since they may be stale
+func fromSession(fn func(store.Context) []string) cobra.CompletionFunc {
+	return fromContext(func(c store.Context) []string {
+		if time.Now().After(c.Expiry) {
+			return nil
+		}
+
+		return fn(c)
+	})
+}
+
+func load(cobraCmd *cobra.Command) (store.Config, bool) {
+	config, err := store.Get(cobraCmd.Context())
+
+	if err != nil {
+		return store.Config{}, false
+	}
+
+	return config, true
+}
+
+// complete filters names by prefix, leaving out the ones already given
+func complete(names []string, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	var matches []string
+
+	for _, name := range names {
+		if strings.HasPrefix(name, toComplete) && !slices.Contains(args, name) {
+			matches = append(matches, name)
+		}
+	}
+
+	slices.Sort(matches)
+
+	return matches, cobra.ShellCompDirectiveNoFileComp
+}
+
+func keys[V any](m map[string]V) []string {
+	names := make([]string, 0, len(m))
+
+	for name := range m {
+		names = append(names, name)
+	}
+
+	return names
+}
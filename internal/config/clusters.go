@@ -0,0 +1,201 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/RiskyFeryansyahP/paycast/internal/completion"
+	"github.com/RiskyFeryansyahP/paycast/internal/store"
+	"github.com/RiskyFeryansyahP/paycast/pkg/cmd"
+	"github.com/RiskyFeryansyahP/paycast/pkg/dryrun"
+	"github.com/RiskyFeryansyahP/paycast/pkg/exit"
+	"github.com/RiskyFeryansyahP/paycast/pkg/logger"
+	"github.com/RiskyFeryansyahP/paycast/pkg/output"
+	"github.com/spf13/cobra"
+)
+
+var configClustersCmd = &cobra.Command{
+	Use:   "clusters",
+	Short: "List clusters available to the current context",
+	Long:  "List the root cluster and trusted leaf clusters of the current context and whether they are declared",
+	Args:  cobra.NoArgs,
+	RunE:  clustersRun,
+}
+
+var configAddClusterCmd = &cobra.Command{
+	Use:   "add-cluster <leaf-cluster>",
+	Short: "Declare a leaf cluster in the current context",
+	Long:  "Declare a trusted leaf cluster so databases can be proxied through it",
+	Args:  cobra.ExactArgs(1),
+	RunE:  addClusterRun,
+}
+
+var configDeleteClusterCmd = &cobra.Command{
+	Use:               "delete-cluster <leaf-cluster>",
+	Short:             "Remove a leaf cluster from the current context",
+	Long:              "Remove a declared leaf cluster from the current context",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completion.First(completion.LeafClusters),
+	RunE:              deleteClusterRun,
+}
+
+func clustersRun(cobraCmd *cobra.Command, args []string) error {
+	ctx := cobraCmd.Context()
+
+	config, currentContext, err := store.LoadCurrent(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	configContext := config.Contexts[currentContext]
+
+	err = configContext.CheckSession(time.Now())
+
+	if err != nil {
+		return err
+	}
+
+	clusters, err := cmd.Clusters(ctx, configContext.TeleportHome())
+
+	if err != nil {
+		return fmt.Errorf("failed to list clusters, check that the context is logged in: %w", err)
+	}
+
+	result := make(ClustersResult, 0, len(clusters))
+
+	for _, cluster := range clusters {
+		result = append(result, ClusterEntry{
+			Name:     cluster.Name,
+			Type:     cluster.Type,
+			Status:   cluster.Status,
+			Declared: cluster.Type == "root" || slices.Contains(configContext.LeafClusters, cluster.Name),
+		})
+	}
+
+	err = output.Print(result)
+
+	if err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+
+	return nil
+}
+
+func addClusterRun(cobraCmd *cobra.Command, args []string) error {
+	ctx := cobraCmd.Context()
+
+	config, currentContext, err := store.LoadCurrent(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	configContext := config.Contexts[currentContext]
+
+	leafCluster := args[0]
+
+	if slices.Contains(configContext.LeafClusters, leafCluster) {
+		logger.Info().
+			Str("cluster", leafCluster).
+			Msg("Leaf cluster already declared")
+
+		err := output.Print(ClusterResult{Action: "unchanged", Context: currentContext, Cluster: leafCluster})
+
+		if err != nil {
+			return fmt.Errorf("failed to write output: %w", err)
+		}
+
+		return nil
+	}
+
+	clusters, err := cmd.Clusters(ctx, configContext.TeleportHome())
+
+	switch {
+	case dryrun.Enabled():
+		// tsh did not run, so there is nothing to check the cluster against
+	case err != nil:
+		logger.Warn().
+			Err(err).
+			Msg("Failed to list clusters, declaring leaf cluster without checking it exists")
+	case !slices.ContainsFunc(clusters, func(cluster cmd.ClusterInfo) bool {
+		return cluster.Name == leafCluster && cluster.Type != "root"
+	}):
+		return exit.New(exit.Failure,
+			fmt.Errorf("leaf cluster '%s' not found", leafCluster),
+			"Run 'paycast config clusters' to see available clusters")
+	}
+
+	configContext.LeafClusters = append(configContext.LeafClusters, leafCluster)
+	config.Contexts[currentContext] = configContext
+
+	err = store.Save(ctx, config)
+
+	if err != nil {
+		return fmt.Errorf("failed to save configuration file: %w", err)
+	}
+
+	logger.Info().
+		Str("context", currentContext).
+		Str("cluster", leafCluster).
+		Msg(dryrun.Message("Leaf cluster added successfully", "Dry run, leaf cluster would be added"))
+
+	err = output.Print(ClusterResult{Action: "added", Context: currentContext, Cluster: leafCluster})
+
+	if err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+
+	return nil
+}
+
+func deleteClusterRun(cobraCmd *cobra.Command, args []string) error {
+	ctx := cobraCmd.Context()
+
+	config, currentContext, err := store.LoadCurrent(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	configContext := config.Contexts[currentContext]
+
+	leafCluster := args[0]
+
+	if !slices.Contains(configContext.LeafClusters, leafCluster) {
+		return exit.New(exit.Failure,
+			fmt.Errorf("leaf cluster '%s' not declared", leafCluster),
+			"Run 'paycast config clusters' to see declared clusters")
+	}
+
+	if owner, ok := configContext.ClusterOwner(leafCluster); ok {
+		return exit.New(exit.Failure,
+			fmt.Errorf("leaf cluster '%s' is used by '%s'", leafCluster, owner),
+			"Delete "+owner+" first")
+	}
+
+	configContext.LeafClusters = slices.DeleteFunc(configContext.LeafClusters, func(name string) bool {
+		return name == leafCluster
+	})
+	config.Contexts[currentContext] = configContext
+
+	err = store.Save(ctx, config)
+
+	if err != nil {
+		return fmt.Errorf("failed to save configuration file: %w", err)
+	}
+
+	logger.Info().
+		Str("context", currentContext).
+		Str("cluster", leafCluster).
+		Msg(dryrun.Message("Leaf cluster deleted successfully", "Dry run, leaf cluster would be deleted"))
+
+	err = output.Print(ClusterResult{Action: "deleted", Context: currentContext, Cluster: leafCluster})
+
+	if err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+
+	return nil
+}
@@ -1,20 +1,18 @@
 package config
 
 import (
-	"bufio"
 	"fmt"
-	"os"
-	"os/exec"
-	"strings"
-	"syscall"
 	"time"
 
+	"github.com/RiskyFeryansyahP/paycast/internal/completion"
+	"github.com/RiskyFeryansyahP/paycast/internal/hook"
 	"github.com/RiskyFeryansyahP/paycast/internal/store"
 	"github.com/RiskyFeryansyahP/paycast/pkg/cmd"
+	"github.com/RiskyFeryansyahP/paycast/pkg/dryrun"
+	"github.com/RiskyFeryansyahP/paycast/pkg/exit"
 	"github.com/RiskyFeryansyahP/paycast/pkg/logger"
-	"github.com/creack/pty"
+	"github.com/RiskyFeryansyahP/paycast/pkg/output"
 	"github.com/spf13/cobra"
-	"golang.org/x/term"
 )
 
 var configCmd = &cobra.Command{
@@ -25,249 +23,231 @@ var configCmd = &cobra.Command{
 }
 
 var configSetContextCmd = &cobra.Command{
-	Use:   "set-context <name> <teleport-url>",
-	Short: "Create or update a context configuration",
-	Long:  "Authenticate to Teleport and save the context configuration for future use",
-	Args:  cobra.ExactArgs(2),
-	Run:   setContextRun,
+	Use:               "set-context <name> <teleport-url>",
+	Short:             "Create or update a context configuration",
+	Long:              "Authenticate to Teleport and save the context configuration for future use",
+	Args:              cobra.ExactArgs(2),
+	ValidArgsFunction: completion.First(completion.Contexts),
+	RunE:              setContextRun,
 }
 
 var configDeleteContextCmd = &cobra.Command{
-	Use:   "delete-context <name>",
-	Short: "Delete a context configuration",
-	Long:  "Remove the specified context from the configuration",
-	Args:  cobra.ExactArgs(1),
-	Run:   deleteContextRun,
+	Use:               "delete-context <name>",
+	Short:             "Delete a context configuration",
+	Long:              "Remove the specified context from the configuration, stopping its proxies and deleting its files under ~/.paycast/contexts",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completion.First(completion.Contexts),
+	RunE:              deleteContextRun,
 }
 
 var configUseContextCmd = &cobra.Command{
-	Use:   "use-context <name>",
-	Short: "Switch to a different context",
-	Long:  "Set the specified context as the current active context",
-	Args:  cobra.ExactArgs(1),
-	Run:   useContextRun,
+	Use:               "use-context <name>",
+	Short:             "Switch to a different context",
+	Long:              "Set the specified context as the current active context",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completion.First(completion.Contexts),
+	RunE:              useContextRun,
 }
 
 func NewConfigCommand() *cobra.Command {
-	var proxy, auth, user string
+	var proxy, auth, user, browser, credentialHelper, environment string
+	var ssoTimeout time.Duration
+	var isolated bool
+	var ttl int
+	var requestIDs, loginArgs []string
 
 	configSetContextCmd.Flags().StringVarP(&proxy, "proxy", "p", "", "Teleport proxy address")
 	configSetContextCmd.Flags().StringVarP(&auth, "auth", "a", "", "Specify the name of authentication connector to use")
 	configSetContextCmd.Flags().StringVarP(&user, "user", "u", "", "Teleport user, defaults to current local")
+	configSetContextCmd.Flags().StringVar(&browser, "browser", "", "Command used to open SSO login URLs, 'none' only prints the URL")
+	configSetContextCmd.Flags().StringVar(&credentialHelper, "credential-helper", "", "Supply login secrets from 'env', 'file', 'file:<path>' or a helper command")
+	configSetContextCmd.Flags().BoolVar(&isolated, "isolated", false, "Keep the tsh profile of this context in its own TELEPORT_HOME under ~/.paycast/contexts")
+	configSetContextCmd.Flags().IntVar(&ttl, "ttl", 0, "Requested session length in minutes, reused on relogin")
+	configSetContextCmd.Flags().StringSliceVar(&requestIDs, "request-id", nil, "Access request IDs to login with, reused on relogin")
+	configSetContextCmd.Flags().StringArrayVar(&loginArgs, "login-arg", nil, "Extra argument passed to tsh login, reused on relogin")
+	configSetContextCmd.Flags().StringVar(&environment, "environment", "", "Environment label of the context, e.g. production, colors 'paycast prompt'")
+	configSetContextCmd.Flags().DurationVar(&ssoTimeout, "sso-timeout", cmd.DefaultSSOTimeout, "How long to wait for an SSO login to complete in the browser")
 	_ = configSetContextCmd.MarkFlagRequired("proxy")
 	_ = configSetContextCmd.MarkFlagRequired("auth")
 	_ = configSetContextCmd.MarkFlagRequired("user")
+	_ = configSetContextCmd.RegisterFlagCompletionFunc("environment", cobra.FixedCompletions([]string{"production", "staging", "development"}, cobra.ShellCompDirectiveNoFileComp))
 
 	configCmd.AddCommand(configSetContextCmd, configDeleteContextCmd, configUseContextCmd)
+	configCmd.AddCommand(configClustersCmd, configAddClusterCmd, configDeleteClusterCmd)
+	configCmd.AddCommand(newHookCommands()...)
 
 	return configCmd
 }
 
-func setContextRun(cobraCmd *cobra.Command, args []string) {
+func setContextRun(cobraCmd *cobra.Command, args []string) error {
 	ctx := cobraCmd.Context()
 
 	proxyFlagVal := cobraCmd.Flag("proxy").Value.String()
 	authFlagVal := cobraCmd.Flag("auth").Value.String()
 	userFlagVal := cobraCmd.Flag("user").Value.String()
+	ssoTimeout, _ := cobraCmd.Flags().GetDuration("sso-timeout")
 
 	contextName := args[0]
 	teleportURL := args[1]
 
-	proxy := fmt.Sprintf("--proxy=%s", proxyFlagVal)
-	auth := fmt.Sprintf("--auth=%s", authFlagVal)
-	user := fmt.Sprintf("--user=%s", userFlagVal)
+	isExists, err := store.IsExist(ctx)
+
+	if err != nil {
+		return fmt.Errorf("failed to check configuration file: %w", err)
+	}
 
-	cmd := exec.Command("tsh", "login", proxy, auth, user, teleportURL)
-	cmd.Env = append(os.Environ(), "TERM=dumb")
+	config := store.Config{
+		Contexts: make(map[string]store.Context),
+	}
 
-	ptyF, err := pty.Start(cmd)
+	if isExists {
+		config, err = store.Get(ctx)
 
-	if err != nil {
-		logger.Fatal().
-			Err(err).
-			Msg("Failed to start terminal session for tsh login")
+		if err != nil {
+			return fmt.Errorf("failed to load configuration file: %w", err)
+		}
+
+		if config.Contexts == nil {
+			config.Contexts = make(map[string]store.Context)
+		}
 	}
-	defer ptyF.Close()
 
-	scanner := bufio.NewScanner(ptyF)
-	result := make(map[string]string)
+	// Keep databases and overrides of an existing context when updating it
+	newContext, updated := config.Contexts[contextName]
+	newContext.Name = contextName
+	newContext.URL = teleportURL
+	newContext.Proxy = proxyFlagVal
+	newContext.Auth = authFlagVal
+	newContext.User = userFlagVal
 
-	for scanner.Scan() {
-		line := scanner.Text()
+	if cobraCmd.Flags().Changed("browser") {
+		newContext.Browser = cobraCmd.Flag("browser").Value.String()
+	}
 
-		fmt.Println(line)
+	if cobraCmd.Flags().Changed("environment") {
+		newContext.Environment = cobraCmd.Flag("environment").Value.String()
+	}
 
-		if strings.Contains(line, "password") {
-			password, err := term.ReadPassword(int(syscall.Stdin))
+	if cobraCmd.Flags().Changed("isolated") {
+		newContext.Isolated, _ = cobraCmd.Flags().GetBool("isolated")
+	}
 
-			if err != nil {
-				logger.Fatal().
-					Err(err).
-					Msg("Failed to read password input")
-			}
+	if cobraCmd.Flags().Changed("credential-helper") {
+		newContext.CredentialHelper = cobraCmd.Flag("credential-helper").Value.String()
+	}
 
-			fmt.Fprintln(ptyF, string(password))
-			continue
-		}
+	if cobraCmd.Flags().Changed("ttl") {
+		newContext.TTL, _ = cobraCmd.Flags().GetInt("ttl")
+	}
 
-		if strings.Contains(line, "OTP") {
-			otp, err := term.ReadPassword(int(syscall.Stdin))
+	if cobraCmd.Flags().Changed("request-id") {
+		newContext.RequestIDs, _ = cobraCmd.Flags().GetStringSlice("request-id")
+	}
 
-			if err != nil {
-				logger.Fatal().
-					Err(err).
-					Msg("Failed to read OTP input")
-			}
+	if cobraCmd.Flags().Changed("login-arg") {
+		newContext.LoginArgs, _ = cobraCmd.Flags().GetStringArray("login-arg")
+	}
 
-			fmt.Fprintln(ptyF, string(otp))
-			continue
-		}
+	spec, err := cmd.NewLoginSpec(newContext)
 
-		if !strings.Contains(line, "password") && !strings.Contains(line, "OTP") {
-			if strings.Contains(line, "Profile") {
-				profile := strings.TrimSpace(strings.Split(line, ": ")[1])
-				result["profile"] = profile
-				continue
-			}
-
-			if strings.Contains(line, "Cluster") {
-				cluster := strings.TrimSpace(strings.Split(line, ": ")[1])
-				result["cluster"] = cluster
-				continue
-			}
-
-			if strings.Contains(line, "Valid") {
-				valid := strings.TrimSpace(strings.Split(line, ": ")[1])
-				validTime := strings.Split(valid, " ")[0:2]
-				result["valid"] = strings.Join(validTime, " ")
-				continue
-			}
-		}
+	if err != nil {
+		return fmt.Errorf("invalid prompt rules in context configuration: %w", err)
 	}
 
-	err = cmd.Wait()
+	spec.SSOTimeout = ssoTimeout
+
+	err = hook.Run(ctx, newContext.Hooks, hook.NewEvent(hook.PreLogin, newContext))
 
 	if err != nil {
-		logger.Fatal().
-			Err(err).
-			Msg("tsh login command failed. Please check your credentials and try again")
+		return fmt.Errorf("login aborted by pre_login hook: %w", err)
 	}
 
-	isExists, err := store.IsExist(ctx)
+	session, err := cmd.Login(ctx, spec)
 
 	if err != nil {
-		logger.Fatal().
-			Err(err).
-			Msg("Failed to check configuration file")
+		return exit.Wrap(exit.AuthFailed, fmt.Errorf("failed to login to teleport: %w", err), cmd.AuthHint)
 	}
 
-	expiry := result["valid"]
-	expiryTime, err := time.Parse(time.DateTime, expiry)
+	err = session.Verify()
 
 	if err != nil {
-		logger.Fatal().
-			Err(err).
-			Str("expiry", expiry).
-			Msg("Failed to parse session expiry time from tsh output")
+		return exit.Wrap(exit.AuthFailed, fmt.Errorf("logged in with unexpected tsh profile: %w", err), cmd.AuthHint)
 	}
 
-	if !isExists {
-		config := store.Config{
-			CurrentContext: contextName,
-			Contexts: map[string]store.Context{
-				contextName: {
-					Name:    contextName,
-					Cluster: result["cluster"],
-					Profile: result["profile"],
-					Expiry:  expiryTime,
-					Proxy:   proxyFlagVal,
-					Auth:    authFlagVal,
-					User:    userFlagVal,
-				},
-			},
-		}
+	session.Apply(&newContext)
+
+	err = hook.Run(ctx, newContext.Hooks, hook.NewEvent(hook.PostLogin, newContext))
+
+	if err != nil {
+		return fmt.Errorf("login aborted by post_login hook: %w", err)
+	}
+
+	config.CurrentContext = contextName
+	config.Contexts[contextName] = newContext
 
+	if !isExists {
 		err = store.New(ctx, config)
 
 		if err != nil {
-			logger.Fatal().
-				Err(err).
-				Msg("Failed to create configuration file")
+			return fmt.Errorf("failed to create configuration file: %w", err)
 		}
+	} else {
+		err = store.Save(ctx, config)
 
-		logger.Info().
-			Str("context", contextName).
-			Str("cluster", result["cluster"]).
-			Msg("Context created successfully")
-		return
+		if err != nil {
+			return fmt.Errorf("failed to save configuration file: %w", err)
+		}
 	}
 
-	config, err := store.Get(ctx)
+	message := dryrun.Message("Context created successfully", "Dry run, context would be created")
+	action := "created"
 
-	if err != nil {
-		logger.Fatal().
-			Err(err).
-			Msg("Failed to load configuration file")
+	if updated {
+		message = dryrun.Message("Context updated successfully", "Dry run, context would be updated")
+		action = "updated"
 	}
 
-	config.CurrentContext = contextName
-	config.Contexts[contextName] = store.Context{
-		Name:    contextName,
-		Cluster: result["cluster"],
-		Profile: result["profile"],
-		Expiry:  expiryTime,
-		Proxy:   proxyFlagVal,
-		Auth:    authFlagVal,
-		User:    userFlagVal,
-	}
+	logger.Info().
+		Str("context", contextName).
+		Str("cluster", newContext.Cluster).
+		Msg(message)
 
-	err = store.Save(ctx, config)
+	err = output.Print(newContextResult(action, newContext))
 
 	if err != nil {
-		logger.Fatal().
-			Err(err).
-			Msg("Failed to save configuration file")
+		return fmt.Errorf("failed to write output: %w", err)
 	}
 
-	logger.Info().
-		Str("context", contextName).
-		Str("cluster", result["cluster"]).
-		Msg("Context updated successfully")
+	return nil
 }
 
-func deleteContextRun(cobraCmd *cobra.Command, args []string) {
+func deleteContextRun(cobraCmd *cobra.Command, args []string) error {
 	ctx := cobraCmd.Context()
 
 	exist, err := store.IsExist(ctx)
 
 	if err != nil {
-		logger.Fatal().
-			Err(err).
-			Msg("Failed to check configuration file")
+		return fmt.Errorf("failed to check configuration file: %w", err)
 	}
 
 	if !exist {
-		logger.Fatal().
-			Err(store.ErrConfigNotFound).
-			Send()
+		return store.ErrConfigNotFound
 	}
 
 	config, err := store.Get(ctx)
 
 	if err != nil {
-		logger.Fatal().
-			Err(err).
-			Msg("Failed to load configuration file")
+		return fmt.Errorf("failed to load configuration file: %w", err)
 	}
 
 	contextName := args[0]
 
-	_, ok := config.Contexts[contextName]
+	deletedContext, ok := config.Contexts[contextName]
 
 	if !ok {
-		logger.Fatal().
-			Err(fmt.Errorf("context '%s' not found", contextName)).
-			Msg("Run 'paycast config set-context' to see available contexts")
+		return exit.New(exit.ContextMissing,
+			fmt.Errorf("context '%s' not found", contextName),
+			"Run 'paycast config set-context' to see available contexts")
 	}
 
 	delete(config.Contexts, contextName)
@@ -275,39 +255,49 @@ func deleteContextRun(cobraCmd *cobra.Command, args []string) {
 	err = store.Save(ctx, config)
 
 	if err != nil {
-		logger.Fatal().
-			Err(err).
-			Msg("Failed to save configuration file")
+		return fmt.Errorf("failed to save configuration file: %w", err)
+	}
+
+	// Proxies of the context would otherwise keep running with its
+	// credentials, which are removed with the context directory
+	store.StopProcesses(contextName)
+
+	err = store.RemoveContextDir(contextName)
+
+	if err != nil {
+		return fmt.Errorf("failed to remove files of context '%s': %w", contextName, err)
 	}
 
 	logger.Info().
 		Str("context", contextName).
-		Msg("Context deleted successfully")
+		Msg(dryrun.Message("Context deleted successfully", "Dry run, context would be deleted"))
+
+	err = output.Print(newContextResult("deleted", deletedContext))
+
+	if err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+
+	return nil
 }
 
-func useContextRun(cobraCmd *cobra.Command, args []string) {
+func useContextRun(cobraCmd *cobra.Command, args []string) error {
 	ctx := cobraCmd.Context()
 
 	exist, err := store.IsExist(ctx)
 
 	if err != nil {
-		logger.Fatal().
-			Err(err).
-			Msg("Failed to check configuration file")
+		return fmt.Errorf("failed to check configuration file: %w", err)
 	}
 
 	if !exist {
-		logger.Fatal().
-			Err(store.ErrConfigNotFound).
-			Send()
+		return store.ErrConfigNotFound
 	}
 
 	config, err := store.Get(ctx)
 
 	if err != nil {
-		logger.Fatal().
-			Err(err).
-			Msg("Failed to load configuration file")
+		return fmt.Errorf("failed to load configuration file: %w", err)
 	}
 
 	contextName := args[0]
@@ -315,29 +305,41 @@ func useContextRun(cobraCmd *cobra.Command, args []string) {
 	contextUsed, ok := config.Contexts[contextName]
 
 	if !ok {
-		logger.Fatal().
-			Err(fmt.Errorf("context '%s' not found", contextName)).
-			Msg("Run 'paycast config set-context' to see available contexts")
+		return exit.New(exit.ContextMissing,
+			fmt.Errorf("context '%s' not found", contextName),
+			"Run 'paycast config set-context' to see available contexts")
 	}
 
 	config.CurrentContext = contextName
 	err = store.Save(ctx, config)
 
 	if err != nil {
-		logger.Fatal().
-			Err(err).
-			Msg("Failed to save configuration file")
+		return fmt.Errorf("failed to save configuration file: %w", err)
+	}
+
+	usedContext, err := cmd.Relogin(ctx, &contextUsed)
+
+	if err != nil {
+		return fmt.Errorf("failed to change to given context: %w", err)
 	}
 
-	_, err = cmd.Relogin(ctx, &contextUsed)
+	config.Contexts[contextName] = *usedContext
+
+	err = store.Save(ctx, config)
 
 	if err != nil {
-		logger.Fatal().
-			Err(err).
-			Msg("Failed to change to given context")
+		return fmt.Errorf("failed to save configuration file: %w", err)
 	}
 
 	logger.Info().
 		Str("context", contextName).
-		Msg("Switched to context successfully")
+		Msg(dryrun.Message("Switched to context successfully", "Dry run, would switch to context"))
+
+	err = output.Print(newContextResult("switched", *usedContext))
+
+	if err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+
+	return nil
 }
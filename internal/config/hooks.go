@@ -0,0 +1,241 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/RiskyFeryansyahP/paycast/internal/completion"
+	"github.com/RiskyFeryansyahP/paycast/internal/hook"
+	"github.com/RiskyFeryansyahP/paycast/internal/store"
+	"github.com/RiskyFeryansyahP/paycast/pkg/dryrun"
+	"github.com/RiskyFeryansyahP/paycast/pkg/exit"
+	"github.com/RiskyFeryansyahP/paycast/pkg/logger"
+	"github.com/RiskyFeryansyahP/paycast/pkg/output"
+	"github.com/spf13/cobra"
+)
+
+var configHooksCmd = &cobra.Command{
+	Use:   "hooks",
+	Short: "List lifecycle hooks",
+	Long:  "List the hooks of the current context and its databases",
+	Args:  cobra.NoArgs,
+	RunE:  hooksRun,
+}
+
+var configAddHookCmd = &cobra.Command{
+	Use:   "add-hook <event> <command>",
+	Short: "Add a lifecycle hook",
+	Long: "Run a shell command when an event happens in the current context. Events are " +
+		"pre_login, post_login, proxy_ready, proxy_exit and session_expiring. Event details are " +
+		"passed as PAYCAST_* environment variables and as JSON on stdin",
+	Args:              cobra.ExactArgs(2),
+	ValidArgsFunction: completion.First(cobra.FixedCompletions(hook.Events, cobra.ShellCompDirectiveNoFileComp)),
+	RunE:              addHookRun,
+}
+
+var configDeleteHookCmd = &cobra.Command{
+	Use:   "delete-hook <index>",
+	Short: "Remove a lifecycle hook",
+	Long:  "Remove a hook by the index shown in 'paycast config hooks'",
+	Args:  cobra.ExactArgs(1),
+	RunE:  deleteHookRun,
+}
+
+func newHookCommands() []*cobra.Command {
+	var db, timeout, before, onFailure string
+
+	configAddHookCmd.Flags().StringVar(&db, "db", "", "Attach the hook to a database tunnel instead of the context")
+	configAddHookCmd.Flags().StringVar(&timeout, "timeout", "", "How long the hook may run, defaults to 30s")
+	configAddHookCmd.Flags().StringVar(&before, "before", "", "How long before expiry session_expiring hooks run, defaults to 10m")
+	configAddHookCmd.Flags().StringVar(&onFailure, "on-failure", hook.OnFailureWarn, "What to do when the hook fails: warn, ignore or abort")
+
+	configDeleteHookCmd.Flags().String("db", "", "Remove the hook from a database tunnel instead of the context")
+	_ = configAddHookCmd.RegisterFlagCompletionFunc("db", completion.Databases)
+	_ = configAddHookCmd.RegisterFlagCompletionFunc("on-failure", cobra.FixedCompletions([]string{hook.OnFailureWarn, hook.OnFailureIgnore, hook.OnFailureAbort}, cobra.ShellCompDirectiveNoFileComp))
+	_ = configDeleteHookCmd.RegisterFlagCompletionFunc("db", completion.Databases)
+
+	return []*cobra.Command{configHooksCmd, configAddHookCmd, configDeleteHookCmd}
+}
+
+func hooksRun(cobraCmd *cobra.Command, args []string) error {
+	config, currentContext, err := store.LoadCurrent(cobraCmd.Context())
+
+	if err != nil {
+		return err
+	}
+
+	configContext := config.Contexts[currentContext]
+
+	result := HooksResult{}
+
+	addHooks := func(scope string, hooks []store.Hook) {
+		for i, h := range hooks {
+			result = append(result, newHookEntry(i, scope, h))
+		}
+	}
+
+	addHooks("context", configContext.Hooks)
+
+	tunnels := make([]string, 0, len(configContext.Database))
+
+	for tunnel := range configContext.Database {
+		tunnels = append(tunnels, tunnel)
+	}
+
+	slices.Sort(tunnels)
+
+	for _, tunnel := range tunnels {
+		addHooks("db/"+tunnel, configContext.Database[tunnel].Hooks)
+	}
+
+	err = output.Print(result)
+
+	if err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+
+	return nil
+}
+
+func addHookRun(cobraCmd *cobra.Command, args []string) error {
+	ctx := cobraCmd.Context()
+
+	tunnel := cobraCmd.Flag("db").Value.String()
+
+	h := store.Hook{
+		Event:     args[0],
+		Command:   args[1],
+		Timeout:   cobraCmd.Flag("timeout").Value.String(),
+		Before:    cobraCmd.Flag("before").Value.String(),
+		OnFailure: cobraCmd.Flag("on-failure").Value.String(),
+	}
+
+	err := hook.Validate(h)
+
+	if err != nil {
+		return err
+	}
+
+	config, currentContext, err := store.LoadCurrent(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	configContext := config.Contexts[currentContext]
+
+	scope := "context"
+	index := len(configContext.Hooks)
+
+	if tunnel == "" {
+		configContext.Hooks = append(configContext.Hooks, h)
+	} else {
+		db, ok := configContext.Database[tunnel]
+
+		if !ok {
+			return exit.New(exit.Failure,
+				fmt.Errorf("database '%s' not found", tunnel),
+				"Run 'paycast db list' to see configured databases")
+		}
+
+		if h.Event != hook.ProxyReady && h.Event != hook.ProxyExit {
+			return fmt.Errorf("database hooks only support %s and %s", hook.ProxyReady, hook.ProxyExit)
+		}
+
+		scope = "db/" + tunnel
+		index = len(db.Hooks)
+
+		db.Hooks = append(db.Hooks, h)
+		configContext.Database[tunnel] = db
+	}
+
+	config.Contexts[currentContext] = configContext
+
+	err = store.Save(ctx, config)
+
+	if err != nil {
+		return fmt.Errorf("failed to save configuration file: %w", err)
+	}
+
+	logger.Info().
+		Str("event", h.Event).
+		Str("db", tunnel).
+		Msg(dryrun.Message("Hook added successfully", "Dry run, hook would be added"))
+
+	err = output.Print(HookResult{Action: "added", HookEntry: newHookEntry(index, scope, h)})
+
+	if err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+
+	return nil
+}
+
+func deleteHookRun(cobraCmd *cobra.Command, args []string) error {
+	ctx := cobraCmd.Context()
+
+	tunnel := cobraCmd.Flag("db").Value.String()
+
+	index, err := strconv.Atoi(args[0])
+
+	if err != nil {
+		return exit.New(exit.Failure,
+			fmt.Errorf("invalid hook index '%s'", args[0]),
+			"Run 'paycast config hooks' to see hook indexes")
+	}
+
+	config, currentContext, err := store.LoadCurrent(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	configContext := config.Contexts[currentContext]
+
+	hooks := configContext.Hooks
+	scope := "context"
+
+	if tunnel != "" {
+		hooks = configContext.Database[tunnel].Hooks
+		scope = "db/" + tunnel
+	}
+
+	if index < 0 || index >= len(hooks) {
+		return exit.New(exit.Failure,
+			fmt.Errorf("hook %d not found", index),
+			"Run 'paycast config hooks' to see hook indexes")
+	}
+
+	deleted := newHookEntry(index, scope, hooks[index])
+	hooks = slices.Delete(slices.Clone(hooks), index, index+1)
+
+	if tunnel == "" {
+		configContext.Hooks = hooks
+	} else {
+		db := configContext.Database[tunnel]
+		db.Hooks = hooks
+		configContext.Database[tunnel] = db
+	}
+
+	config.Contexts[currentContext] = configContext
+
+	err = store.Save(ctx, config)
+
+	if err != nil {
+		return fmt.Errorf("failed to save configuration file: %w", err)
+	}
+
+	logger.Info().
+		Int("index", index).
+		Str("db", tunnel).
+		Msg(dryrun.Message("Hook deleted successfully", "Dry run, hook would be deleted"))
+
+	err = output.Print(HookResult{Action: "deleted", HookEntry: deleted})
+
+	if err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,162 @@
+package config
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/RiskyFeryansyahP/paycast/internal/store"
+)
+
+// ContextResult is the output of set-context, use-context and delete-context.
+// Action is one of created, updated, switched or deleted
+type ContextResult struct {
+	Action      string    `json:"action"`
+	Name        string    `json:"name"`
+	URL         string    `json:"url"`
+	Proxy       string    `json:"proxy"`
+	Cluster     string    `json:"cluster"`
+	User        string    `json:"user"`
+	Environment string    `json:"environment"`
+	Isolated    bool      `json:"isolated"`
+	Expiry      time.Time `json:"expiry"`
+}
+
+func newContextResult(action string, configContext store.Context) ContextResult {
+	return ContextResult{
+		Action:      action,
+		Name:        configContext.Name,
+		URL:         configContext.URL,
+		Proxy:       configContext.Proxy,
+		Cluster:     configContext.Cluster,
+		User:        configContext.User,
+		Environment: configContext.Environment,
+		Isolated:    configContext.Isolated,
+		Expiry:      configContext.Expiry,
+	}
+}
+
+func (r ContextResult) Header() []string {
+	return []string{"ACTION", "NAME", "CLUSTER", "USER", "PROXY", "EXPIRY"}
+}
+
+func (r ContextResult) Rows() [][]string {
+	return [][]string{{r.Action, r.Name, r.Cluster, r.User, r.Proxy, formatTime(r.Expiry)}}
+}
+
+func (r ContextResult) Text() string {
+	return ""
+}
+
+// ClustersResult is the output of 'config clusters'
+type ClustersResult []ClusterEntry
+
+// ClusterEntry is a cluster reachable from the context, Declared tells
+// whether paycast may route through it
+type ClusterEntry struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Status   string `json:"status"`
+	Declared bool   `json:"declared"`
+}
+
+func (r ClustersResult) Header() []string {
+	return []string{"NAME", "TYPE", "STATUS", "DECLARED"}
+}
+
+func (r ClustersResult) Rows() [][]string {
+	rows := make([][]string, 0, len(r))
+
+	for _, cluster := range r {
+		rows = append(rows, []string{cluster.Name, cluster.Type, cluster.Status, strconv.FormatBool(cluster.Declared)})
+	}
+
+	return rows
+}
+
+// ClusterResult is the output of add-cluster and delete-cluster. Action is
+// one of added, unchanged or deleted
+type ClusterResult struct {
+	Action  string `json:"action"`
+	Context string `json:"context"`
+	Cluster string `json:"cluster"`
+}
+
+func (r ClusterResult) Header() []string {
+	return []string{"ACTION", "CONTEXT", "CLUSTER"}
+}
+
+func (r ClusterResult) Rows() [][]string {
+	return [][]string{{r.Action, r.Context, r.Cluster}}
+}
+
+func (r ClusterResult) Text() string {
+	return ""
+}
+
+// HooksResult is the output of 'config hooks'
+type HooksResult []HookEntry
+
+// HookEntry is a hook of the context, or of a database when Scope is
+// db/<tunnel>. Index is its position within the scope
+type HookEntry struct {
+	Index     int    `json:"index"`
+	Scope     string `json:"scope"`
+	Event     string `json:"event"`
+	Command   string `json:"command"`
+	Timeout   string `json:"timeout,omitempty"`
+	Before    string `json:"before,omitempty"`
+	OnFailure string `json:"on_failure,omitempty"`
+}
+
+func newHookEntry(index int, scope string, h store.Hook) HookEntry {
+	return HookEntry{
+		Index:     index,
+		Scope:     scope,
+		Event:     h.Event,
+		Command:   h.Command,
+		Timeout:   h.Timeout,
+		Before:    h.Before,
+		OnFailure: h.OnFailure,
+	}
+}
+
+func (r HooksResult) Header() []string {
+	return []string{"INDEX", "SCOPE", "EVENT", "COMMAND", "TIMEOUT", "ON FAILURE"}
+}
+
+func (r HooksResult) Rows() [][]string {
+	rows := make([][]string, 0, len(r))
+
+	for _, h := range r {
+		rows = append(rows, []string{strconv.Itoa(h.Index), h.Scope, h.Event, h.Command, h.Timeout, h.OnFailure})
+	}
+
+	return rows
+}
+
+// HookResult is the output of add-hook and delete-hook. Action is one of
+// added or deleted
+type HookResult struct {
+	Action string `json:"action"`
+	HookEntry
+}
+
+func (r HookResult) Header() []string {
+	return append([]string{"ACTION"}, HooksResult{}.Header()...)
+}
+
+func (r HookResult) Rows() [][]string {
+	return [][]string{append([]string{r.Action}, HooksResult{r.HookEntry}.Rows()[0]...)}
+}
+
+func (r HookResult) Text() string {
+	return ""
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	return t.Local().Format(time.RFC3339)
+}
@@ -2,17 +2,17 @@ package database
 
 import (
 	"fmt"
-	"os"
-	"os/exec"
-	"os/signal"
+	"slices"
 	"strconv"
-	"syscall"
-	"time"
+	"strings"
 
+	"github.com/RiskyFeryansyahP/paycast/internal/completion"
 	"github.com/RiskyFeryansyahP/paycast/internal/store"
-	"github.com/RiskyFeryansyahP/paycast/pkg/cmd"
+	"github.com/RiskyFeryansyahP/paycast/internal/supervisor"
+	"github.com/RiskyFeryansyahP/paycast/pkg/dryrun"
+	"github.com/RiskyFeryansyahP/paycast/pkg/exit"
 	"github.com/RiskyFeryansyahP/paycast/pkg/logger"
-	"github.com/creack/pty"
+	"github.com/RiskyFeryansyahP/paycast/pkg/output"
 	"github.com/spf13/cobra"
 )
 
@@ -27,242 +27,269 @@ var dbRunCmd = &cobra.Command{
 	Use:   "run",
 	Short: "Start all configured database proxies",
 	Long:  "Start database proxy connections for all databases configured in the current context",
-	Run:   dbRun,
+	RunE:  dbRun,
 }
 
 var dbAddCmd = &cobra.Command{
 	Use:   "add",
 	Short: "Add a new database proxy configuration",
 	Long:  "Configure a new database proxy to be managed by paycast",
-	Run:   dbAddRun,
+	RunE:  dbAddRun,
 }
 
 var dbDeleteCmd = &cobra.Command{
-	Use:   "delete",
-	Short: "Remove a database proxy configuration",
-	Long:  "Delete the specified database configuration from the current context",
-	Run: func(cmd *cobra.Command, args []string) {
-		logger.Warn().Msg("This command is not yet implemented")
-	},
+	Use:               "delete <tunnel>",
+	Short:             "Remove a database proxy configuration",
+	Long:              "Delete the specified database configuration from the current context",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completion.First(completion.Databases),
+	RunE:              dbDeleteRun,
+}
+
+var dbListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List database proxy configurations",
+	Long:  "List the databases configured in the current context",
+	Args:  cobra.NoArgs,
+	RunE:  dbListRun,
 }
 
 func NewConfigCommand() *cobra.Command {
-	var dbUser, dbName, tunnel string
+	var dbUser, dbName, tunnel, cluster string
 	var port int32
 
 	dbAddCmd.Flags().StringVarP(&dbUser, "db-user", "u", "", "Database user to log in as")
 	dbAddCmd.Flags().StringVarP(&dbName, "db-name", "n", "", "Database name to log in to")
 	dbAddCmd.Flags().StringVarP(&tunnel, "tunnel", "", "", "Open authenticated tunnel using database's client certificate so clients don't need to authenticate")
+	dbAddCmd.Flags().StringVarP(&cluster, "cluster", "c", "", "Leaf cluster the database is reached through, defaults to the root cluster")
 	dbAddCmd.Flags().Int32VarP(&port, "port", "p", 0, "Specifies the source port used by proxy db listener")
+	_ = dbAddCmd.RegisterFlagCompletionFunc("db-user", completion.DBUsers)
+	_ = dbAddCmd.RegisterFlagCompletionFunc("db-name", completion.DBNames)
+	_ = dbAddCmd.RegisterFlagCompletionFunc("cluster", completion.LeafClusters)
 	_ = dbAddCmd.MarkFlagRequired("db-user")
 	_ = dbAddCmd.MarkFlagRequired("tunnel")
 	_ = dbAddCmd.MarkFlagRequired("port")
 
-	databaseCmd.AddCommand(dbAddCmd, dbDeleteCmd, dbRunCmd)
+	databaseCmd.AddCommand(dbAddCmd, dbDeleteCmd, dbListCmd, dbRunCmd)
 
 	return databaseCmd
 }
 
-func dbAddRun(cobraCmd *cobra.Command, args []string) {
+func dbAddRun(cobraCmd *cobra.Command, args []string) error {
 	ctx := cobraCmd.Context()
 
 	dbUser := cobraCmd.Flag("db-user").Value.String()
 	dbName := cobraCmd.Flag("db-name").Value.String()
 	tunnel := cobraCmd.Flag("tunnel").Value.String()
 	portStr := cobraCmd.Flag("port").Value.String()
+	cluster := cobraCmd.Flag("cluster").Value.String()
 
 	port, _ := strconv.Atoi(portStr)
 
-	exists, err := store.IsExist(ctx)
+	config, currentContext, err := store.LoadCurrent(ctx)
 
 	if err != nil {
-		logger.Fatal().
-			Err(err).
-			Msg("Failed to check configuration file")
+		return err
 	}
 
-	if !exists {
-		logger.Fatal().
-			Err(store.ErrConfigNotFound).
-			Send()
-	}
+	configContext := config.Contexts[currentContext]
 
-	config, err := store.Get(ctx)
+	cluster, err = configContext.LeafCluster(cluster)
 
 	if err != nil {
-		logger.Fatal().
-			Err(err).
-			Msg("Failed to load configuration file")
+		return err
 	}
 
-	currentContext := config.CurrentContext
+	owner, taken := configContext.PortOwner(int32(port))
 
-	if currentContext == "" {
-		logger.Fatal().
-			Err(store.ErrNoContext).
-			Send()
+	if taken && owner != "db/"+tunnel {
+		return exit.New(exit.PortInUse,
+			fmt.Errorf("port %d is already used by %s", port, owner),
+			"Choose a different port")
 	}
 
-	configContext := config.Contexts[currentContext]
-
 	if len(configContext.Database) == 0 {
 		configContext.Database = make(map[string]store.Database)
 	}
 
-	configContext.Database[tunnel] = store.Database{
-		User:   dbUser,
-		Tunnel: tunnel,
-		Name:   dbName,
-		Port:   int32(port),
+	db := store.Database{
+		User:    dbUser,
+		Tunnel:  tunnel,
+		Name:    dbName,
+		Port:    int32(port),
+		Cluster: cluster,
+		Hooks:   configContext.Database[tunnel].Hooks,
 	}
+
+	configContext.Database[tunnel] = db
 	config.Contexts[currentContext] = configContext
 
 	err = store.Save(ctx, config)
 
 	if err != nil {
-		logger.Fatal().
-			Err(err).
-			Msg("Failed to save configuration file")
+		return fmt.Errorf("failed to save configuration file: %w", err)
 	}
 
 	logger.Info().
 		Str("tunnel", tunnel).
 		Str("database", dbName).
 		Int("port", port).
-		Msg("Database added successfully")
+		Msg(dryrun.Message("Database added successfully", "Dry run, database would be added"))
+
+	err = output.Print(DatabaseResult{Action: "added", Context: currentContext, DatabaseEntry: newDatabaseEntry(db)})
+
+	if err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+
+	return nil
 }
 
-func dbRun(cobraCmd *cobra.Command, args []string) {
+func dbDeleteRun(cobraCmd *cobra.Command, args []string) error {
 	ctx := cobraCmd.Context()
 
-	exists, err := store.IsExist(ctx)
+	config, currentContext, err := store.LoadCurrent(ctx)
 
 	if err != nil {
-		logger.Fatal().
-			Err(err).
-			Msg("Failed to check configuration file")
+		return err
+	}
+
+	configContext := config.Contexts[currentContext]
+
+	tunnel := args[0]
+
+	db, ok := configContext.Database[tunnel]
+
+	if !ok {
+		return exit.New(exit.Failure,
+			fmt.Errorf("database '%s' not found", tunnel),
+			"Run 'paycast db list' to see configured databases")
+	}
+
+	if workspace, ok := configContext.WorkspaceUsing("db/" + tunnel); ok {
+		return exit.New(exit.Failure,
+			fmt.Errorf("database '%s' is used by workspace '%s'", tunnel, workspace),
+			"Update the workspace with 'paycast workspace create' or delete it first")
 	}
 
-	if !exists {
-		logger.Fatal().
-			Err(store.ErrConfigNotFound).
-			Send()
+	delete(configContext.Database, tunnel)
+	config.Contexts[currentContext] = configContext
+
+	err = store.Save(ctx, config)
+
+	if err != nil {
+		return fmt.Errorf("failed to save configuration file: %w", err)
 	}
 
-	config, err := store.Get(ctx)
+	logger.Info().
+		Str("tunnel", tunnel).
+		Msg(dryrun.Message("Database deleted successfully", "Dry run, database would be deleted"))
+
+	err = output.Print(DatabaseResult{Action: "deleted", Context: currentContext, DatabaseEntry: newDatabaseEntry(db)})
 
 	if err != nil {
-		logger.Fatal().
-			Err(err).
-			Msg("Failed to load configuration file")
+		return fmt.Errorf("failed to write output: %w", err)
 	}
 
-	currentContext := config.CurrentContext
+	return nil
+}
+
+func dbListRun(cobraCmd *cobra.Command, args []string) error {
+	config, currentContext, err := store.LoadCurrent(cobraCmd.Context())
 
-	if currentContext == "" {
-		logger.Fatal().
-			Err(store.ErrNoContext).
-			Send()
+	if err != nil {
+		return err
 	}
 
 	configContext := config.Contexts[currentContext]
 
-	now := time.Now()
+	tunnels := make([]string, 0, len(configContext.Database))
+
+	for tunnel := range configContext.Database {
+		tunnels = append(tunnels, tunnel)
+	}
 
-	if now.After(configContext.Expiry) {
-		updatedConfigContext, err := cmd.Relogin(ctx, &configContext)
+	slices.Sort(tunnels)
 
-		if err != nil {
-			logger.Fatal().
-				Err(err).
-				Msg("Failed to relogin to set context when context expired")
-		}
+	result := make(DatabasesResult, 0, len(tunnels))
+
+	for _, tunnel := range tunnels {
+		result = append(result, newDatabaseEntry(configContext.Database[tunnel]))
+	}
+
+	err = output.Print(result)
+
+	if err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+
+	return nil
+}
+
+func dbRun(cobraCmd *cobra.Command, args []string) error {
+	ctx := cobraCmd.Context()
+
+	config, currentContext, err := store.LoadCurrent(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	configContext := config.Contexts[currentContext]
+
+	proxies := make([]supervisor.Proxy, 0, len(configContext.Database))
+	result := make(DatabasesResult, 0, len(configContext.Database))
 
-		config.Contexts[currentContext] = *updatedConfigContext
+	for _, db := range configContext.Database {
+		proxies = append(proxies, Proxy(db))
+		result = append(result, newDatabaseEntry(db))
+	}
+
+	// Structured output announces the databases served up front, text
+	// output has the log line of every proxy instead
+	if output.Structured() {
+		slices.SortFunc(result, func(a, b DatabaseEntry) int {
+			return strings.Compare(a.Tunnel, b.Tunnel)
+		})
 
-		err = store.Save(ctx, config)
+		err = output.Print(result)
 
 		if err != nil {
-			logger.Fatal().
-				Err(err).
-				Msg("Failed to save configuration file")
+			return fmt.Errorf("failed to write output: %w", err)
 		}
 	}
 
-	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
-
-	go func() {
-		for {
-			for _, v := range configContext.Database {
-				go func(db store.Database) {
-					dbUser := fmt.Sprintf("--db-user=%s", db.User)
-					dbName := fmt.Sprintf("--db-name=%s", db.Name)
-					tunnel := fmt.Sprintf("--tunnel=%s", db.Tunnel)
-					port := fmt.Sprintf("--port=%d", db.Port)
-
-					cmd := exec.Command("tsh", "proxy", "db", dbUser, dbName, tunnel, port)
-					cmd.Env = append(os.Environ(), "TERM=dumb")
-
-					ptyF, err := pty.Start(cmd)
-
-					if err != nil {
-						logger.Fatal().
-							Err(err).
-							Str("database", v.Name).
-							Msg("Failed to start database proxy")
-					}
-					defer ptyF.Close()
-
-					logger.Info().
-						Str("user", v.User).
-						Str("database", v.Name).
-						Int("port", int(v.Port)).
-						Str("host", "localhost").
-						Msg("Database proxy started")
-
-					err = cmd.Wait()
-
-					if err != nil {
-						logger.Error().
-							Err(err).
-							Str("database", v.Name).
-							Msg("Database proxy terminated with error")
-					}
-				}(v)
-			}
-
-			duration := configContext.Expiry.Sub(now)
-
-			timer := time.NewTimer(duration)
-
-			select {
-			case <-ctx.Done():
-				return
-			case <-timer.C:
-				updatedConfigContext, err := cmd.Relogin(ctx, &configContext)
-
-				if err != nil {
-					logger.Fatal().
-						Err(err).
-						Msg("Failed to relogin to set context when context expired")
-				}
-
-				config.Contexts[currentContext] = *updatedConfigContext
-
-				err = store.Save(ctx, config)
-
-				if err != nil {
-					logger.Fatal().
-						Err(err).
-						Msg("Failed to save configuration file")
-				}
-			}
-		}
-	}()
+	err = supervisor.Run(ctx, config, currentContext, proxies)
+
+	if err != nil {
+		return fmt.Errorf("failed to run database proxies: %w", err)
+	}
+
+	return nil
+}
 
-	// Block until we receive our signal.
-	<-c
+// Proxy describes the tsh proxy serving a database
+func Proxy(db store.Database) supervisor.Proxy {
+	dbUser := fmt.Sprintf("--db-user=%s", db.User)
+	dbName := fmt.Sprintf("--db-name=%s", db.Name)
+	tunnel := fmt.Sprintf("--tunnel=%s", db.Tunnel)
+	port := fmt.Sprintf("--port=%d", db.Port)
 
-	logger.Info().Msg("Shutting down database proxies...")
+	args := []string{"proxy", "db", dbUser, dbName, tunnel, port}
+
+	if db.Cluster != "" {
+		args = append(args, fmt.Sprintf("--cluster=%s", db.Cluster))
+	}
+
+	return supervisor.Proxy{
+		Kind:  "db",
+		Name:  db.Tunnel,
+		Ports: []int32{db.Port},
+		Args:  args,
+		Fields: map[string]string{
+			"user":     db.User,
+			"database": db.Name,
+		},
+		Hooks: db.Hooks,
+	}
 }
@@ -0,0 +1,63 @@
+package database
+
+import (
+	"strconv"
+
+	"github.com/RiskyFeryansyahP/paycast/internal/store"
+)
+
+// DatabasesResult is the output of 'db list'
+type DatabasesResult []DatabaseEntry
+
+// DatabaseEntry is a database of the context served on a local port
+type DatabaseEntry struct {
+	Tunnel   string `json:"tunnel"`
+	Database string `json:"database"`
+	User     string `json:"user"`
+	Port     int32  `json:"port"`
+	Cluster  string `json:"cluster,omitempty"`
+}
+
+func newDatabaseEntry(db store.Database) DatabaseEntry {
+	return DatabaseEntry{
+		Tunnel:   db.Tunnel,
+		Database: db.Name,
+		User:     db.User,
+		Port:     db.Port,
+		Cluster:  db.Cluster,
+	}
+}
+
+func (r DatabasesResult) Header() []string {
+	return []string{"TUNNEL", "DATABASE", "USER", "PORT", "CLUSTER"}
+}
+
+func (r DatabasesResult) Rows() [][]string {
+	rows := make([][]string, 0, len(r))
+
+	for _, db := range r {
+		rows = append(rows, []string{db.Tunnel, db.Database, db.User, strconv.Itoa(int(db.Port)), db.Cluster})
+	}
+
+	return rows
+}
+
+// DatabaseResult is the output of 'db add' and 'db delete'. Action is one of
+// added or deleted
+type DatabaseResult struct {
+	Action  string `json:"action"`
+	Context string `json:"context"`
+	DatabaseEntry
+}
+
+func (r DatabaseResult) Header() []string {
+	return append([]string{"ACTION", "CONTEXT"}, DatabasesResult{}.Header()...)
+}
+
+func (r DatabaseResult) Rows() [][]string {
+	return [][]string{append([]string{r.Action, r.Context}, DatabasesResult{r.DatabaseEntry}.Rows()[0]...)}
+}
+
+func (r DatabaseResult) Text() string {
+	return ""
+}
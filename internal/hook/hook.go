@@ -0,0 +1,254 @@
+package hook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+
+	"github.com/RiskyFeryansyahP/paycast/internal/store"
+	"github.com/RiskyFeryansyahP/paycast/pkg/dryrun"
+	"github.com/RiskyFeryansyahP/paycast/pkg/logger"
+)
+
+// Events a hook can be attached to
+const (
+	PreLogin        = "pre_login"
+	PostLogin       = "post_login"
+	ProxyReady      = "proxy_ready"
+	ProxyExit       = "proxy_exit"
+	SessionExpiring = "session_expiring"
+)
+
+// Failure policies of a hook
+const (
+	OnFailureWarn   = "warn"
+	OnFailureIgnore = "ignore"
+	OnFailureAbort  = "abort"
+)
+
+const (
+	// DefaultTimeout bounds a hook without its own timeout
+	DefaultTimeout = 30 * time.Second
+
+	// DefaultExpiringBefore is how long before expiry session_expiring hooks run
+	DefaultExpiringBefore = 10 * time.Minute
+)
+
+// Events lists every event hooks can be attached to
+var Events = []string{PreLogin, PostLogin, ProxyReady, ProxyExit, SessionExpiring}
+
+// Event describes what happened, it is passed to hooks as JSON on stdin and
+// as PAYCAST_* environment variables. TeleportHome and Kubeconfig are only
+// passed as TELEPORT_HOME and KUBECONFIG, so tsh and kubectl in a hook use
+// the profile of the context
+type Event struct {
+	Name         string    `json:"event"`
+	Context      string    `json:"context"`
+	Cluster      string    `json:"cluster,omitempty"`
+	User         string    `json:"user,omitempty"`
+	Kind         string    `json:"kind,omitempty"`
+	Target       string    `json:"target,omitempty"`
+	Port         int32     `json:"port,omitempty"`
+	Workspace    string    `json:"workspace,omitempty"`
+	Expiry       time.Time `json:"expiry,omitempty"`
+	Error        string    `json:"error,omitempty"`
+	TeleportHome string    `json:"-"`
+	Kubeconfig   string    `json:"-"`
+}
+
+// NewEvent fills the event details shared by every event of a context
+func NewEvent(name string, configContext store.Context) Event {
+	return Event{
+		Name:         name,
+		Context:      configContext.Name,
+		Cluster:      configContext.Cluster,
+		User:         configContext.User,
+		Expiry:       configContext.Expiry,
+		TeleportHome: configContext.TeleportHome(),
+		Kubeconfig:   configContext.Kubeconfig(),
+	}
+}
+
+func (e Event) environ() []string {
+	env := []string{
+		"PAYCAST_EVENT=" + e.Name,
+		"PAYCAST_CONTEXT=" + e.Context,
+		"PAYCAST_CLUSTER=" + e.Cluster,
+		"PAYCAST_USER=" + e.User,
+		"PAYCAST_KIND=" + e.Kind,
+		"PAYCAST_TARGET=" + e.Target,
+		"PAYCAST_WORKSPACE=" + e.Workspace,
+		"PAYCAST_ERROR=" + e.Error,
+	}
+
+	if e.TeleportHome != "" {
+		env = append(env, "TELEPORT_HOME="+e.TeleportHome)
+	}
+
+	if e.Kubeconfig != "" {
+		env = append(env, "KUBECONFIG="+e.Kubeconfig)
+	}
+
+	if e.Port != 0 {
+		env = append(env, "PAYCAST_PORT="+strconv.Itoa(int(e.Port)))
+	}
+
+	if !e.Expiry.IsZero() {
+		env = append(env, "PAYCAST_EXPIRY="+e.Expiry.UTC().Format(time.RFC3339))
+	}
+
+	return env
+}
+
+// Validate checks the event, timeout and failure policy of a hook
+func Validate(h store.Hook) error {
+	known := false
+
+	for _, event := range Events {
+		known = known || h.Event == event
+	}
+
+	if !known {
+		return fmt.Errorf("unknown hook event '%s'", h.Event)
+	}
+
+	if h.Command == "" {
+		return fmt.Errorf("hook for '%s' has no command", h.Event)
+	}
+
+	switch h.OnFailure {
+	case "", OnFailureWarn, OnFailureIgnore, OnFailureAbort:
+	default:
+		return fmt.Errorf("unknown failure policy '%s'", h.OnFailure)
+	}
+
+	for _, value := range []string{h.Timeout, h.Before} {
+		if value == "" {
+			continue
+		}
+
+		_, err := time.ParseDuration(value)
+
+		if err != nil {
+			return fmt.Errorf("invalid duration '%s' in hook: %w", value, err)
+		}
+	}
+
+	return nil
+}
+
+// ExpiringBefore returns how long before expiry the session_expiring hooks
+// want to run, the longest lead of all of them
+func ExpiringBefore(hooks []store.Hook) time.Duration {
+	before := time.Duration(0)
+
+	for _, h := range hooks {
+		if h.Event != SessionExpiring {
+			continue
+		}
+
+		lead := parseDuration(h.Before, DefaultExpiringBefore)
+
+		if lead > before {
+			before = lead
+		}
+	}
+
+	return before
+}
+
+// Run executes the hooks attached to the event one after another. An error is
+// only returned for a failing hook with the abort policy, other failures are
+// logged according to their policy
+func Run(ctx context.Context, hooks []store.Hook, event Event) error {
+	for _, h := range hooks {
+		if h.Event != event.Name {
+			continue
+		}
+
+		if dryrun.Enabled() {
+			logger.Info().
+				Str("event", event.Name).
+				Str("hook", h.Command).
+				Msg("Dry run, not running hook")
+
+			continue
+		}
+
+		err := run(ctx, h, event)
+
+		if err == nil {
+			continue
+		}
+
+		switch h.OnFailure {
+		case OnFailureIgnore:
+			continue
+		case OnFailureAbort:
+			logger.Error().
+				Err(err).
+				Str("event", event.Name).
+				Str("hook", h.Command).
+				Msg("Hook failed, aborting")
+
+			return fmt.Errorf("%s hook '%s' failed: %w", event.Name, h.Command, err)
+		default:
+			logger.Warn().
+				Err(err).
+				Str("event", event.Name).
+				Str("hook", h.Command).
+				Msg("Hook failed")
+		}
+	}
+
+	return nil
+}
+
+func run(ctx context.Context, h store.Hook, event Event) error {
+	ctx, cancel := context.WithTimeout(ctx, parseDuration(h.Timeout, DefaultTimeout))
+	defer cancel()
+
+	payload, err := json.Marshal(event)
+
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", h.Command)
+	cmd.Env = append(os.Environ(), event.environ()...)
+	cmd.Stdin = bytes.NewReader(payload)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+
+	logger.Debug().
+		Str("event", event.Name).
+		Str("hook", h.Command).
+		Msg("Running hook")
+
+	err = cmd.Run()
+
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("timed out: %w", ctx.Err())
+	}
+
+	return err
+}
+
+func parseDuration(value string, fallback time.Duration) time.Duration {
+	if value == "" {
+		return fallback
+	}
+
+	duration, err := time.ParseDuration(value)
+
+	if err != nil {
+		return fallback
+	}
+
+	return duration
+}
@@ -0,0 +1,249 @@
+package hook
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RiskyFeryansyahP/paycast/internal/store"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		hook    store.Hook
+		wantErr bool
+	}{
+		{
+			name: "minimal",
+			hook: store.Hook{Event: PostLogin, Command: "true"},
+		},
+		{
+			name: "every field",
+			hook: store.Hook{Event: SessionExpiring, Command: "true", Timeout: "5s", Before: "15m", OnFailure: OnFailureAbort},
+		},
+		{
+			name:    "unknown event",
+			hook:    store.Hook{Event: "post_logout", Command: "true"},
+			wantErr: true,
+		},
+		{
+			name:    "no command",
+			hook:    store.Hook{Event: PreLogin},
+			wantErr: true,
+		},
+		{
+			name:    "unknown failure policy",
+			hook:    store.Hook{Event: PreLogin, Command: "true", OnFailure: "retry"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid timeout",
+			hook:    store.Hook{Event: PreLogin, Command: "true", Timeout: "5"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid before",
+			hook:    store.Hook{Event: SessionExpiring, Command: "true", Before: "soon"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.hook)
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestExpiringBefore(t *testing.T) {
+	tests := []struct {
+		name  string
+		hooks []store.Hook
+		want  time.Duration
+	}{
+		{
+			name: "no hooks",
+		},
+		{
+			name:  "other events only",
+			hooks: []store.Hook{{Event: PostLogin, Before: "1h"}},
+		},
+		{
+			name:  "default lead",
+			hooks: []store.Hook{{Event: SessionExpiring}},
+			want:  DefaultExpiringBefore,
+		},
+		{
+			name: "longest lead wins",
+			hooks: []store.Hook{
+				{Event: SessionExpiring, Before: "5m"},
+				{Event: SessionExpiring, Before: "30m"},
+				{Event: PostLogin, Before: "2h"},
+			},
+			want: 30 * time.Minute,
+		},
+		{
+			name:  "invalid lead falls back to the default",
+			hooks: []store.Hook{{Event: SessionExpiring, Before: "soon"}},
+			want:  DefaultExpiringBefore,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ExpiringBefore(tt.hooks)
+
+			if got != tt.want {
+				t.Errorf("ExpiringBefore() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	event := Event{
+		Name:         ProxyReady,
+		Context:      "staging",
+		TeleportHome: "/tmp/paycast/staging/tsh",
+		Kubeconfig:   "/tmp/paycast/staging/kubeconfig",
+	}
+
+	tests := []struct {
+		name    string
+		hooks   []store.Hook
+		wantErr string
+	}{
+		{
+			name:  "success",
+			hooks: []store.Hook{{Event: ProxyReady, Command: "true", OnFailure: OnFailureAbort}},
+		},
+		{
+			name:  "warn on failure",
+			hooks: []store.Hook{{Event: ProxyReady, Command: "false"}},
+		},
+		{
+			name:  "ignore failure",
+			hooks: []store.Hook{{Event: ProxyReady, Command: "false", OnFailure: OnFailureIgnore}},
+		},
+		{
+			name:    "abort on failure",
+			hooks:   []store.Hook{{Event: ProxyReady, Command: "exit 3", OnFailure: OnFailureAbort}},
+			wantErr: "exit status 3",
+		},
+		{
+			name:  "other event not run",
+			hooks: []store.Hook{{Event: ProxyExit, Command: "false", OnFailure: OnFailureAbort}},
+		},
+		{
+			name: "later hooks not run after abort",
+			hooks: []store.Hook{
+				{Event: ProxyReady, Command: "false", OnFailure: OnFailureAbort},
+				{Event: ProxyReady, Command: "exit 4", OnFailure: OnFailureAbort},
+			},
+			wantErr: "exit status 1",
+		},
+		{
+			name:    "timeout",
+			hooks:   []store.Hook{{Event: ProxyReady, Command: "exec sleep 5", Timeout: "50ms", OnFailure: OnFailureAbort}},
+			wantErr: "timed out",
+		},
+		{
+			name:  "timeout warned",
+			hooks: []store.Hook{{Event: ProxyReady, Command: "exec sleep 5", Timeout: "50ms"}},
+		},
+		{
+			name: "profile of the context",
+			hooks: []store.Hook{{
+				Event:     ProxyReady,
+				Command:   `test "$TELEPORT_HOME" = /tmp/paycast/staging/tsh && test "$KUBECONFIG" = /tmp/paycast/staging/kubeconfig`,
+				OnFailure: OnFailureAbort,
+			}},
+		},
+		{
+			name: "event on stdin",
+			hooks: []store.Hook{{
+				Event:     ProxyReady,
+				Command:   `grep -q '"context":"staging"'`,
+				OnFailure: OnFailureAbort,
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Run(context.Background(), tt.hooks, event)
+
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Run() error = %v, want nil", err)
+				}
+
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Run() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEventEnviron(t *testing.T) {
+	tests := []struct {
+		name    string
+		event   Event
+		want    []string
+		notWant []string
+	}{
+		{
+			name: "isolated context",
+			event: NewEvent(PostLogin, store.Context{
+				Name:     "staging",
+				Isolated: true,
+			}),
+			want: []string{
+				"PAYCAST_CONTEXT=staging",
+				"TELEPORT_HOME=" + store.GetContextDir("staging") + "/tsh",
+				"KUBECONFIG=" + store.GetContextDir("staging") + "/kubeconfig",
+			},
+		},
+		{
+			name:    "shared profile",
+			event:   NewEvent(PostLogin, store.Context{Name: "staging"}),
+			want:    []string{"KUBECONFIG=" + store.GetContextDir("staging") + "/kubeconfig"},
+			notWant: []string{"TELEPORT_HOME"},
+		},
+		{
+			name:  "proxy port",
+			event: Event{Name: ProxyReady, Port: 5432},
+			want:  []string{"PAYCAST_PORT=5432"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := tt.event.environ()
+
+			for _, want := range tt.want {
+				if !slices.Contains(env, want) {
+					t.Errorf("environ() = %v, missing %q", env, want)
+				}
+			}
+
+			for _, name := range tt.notWant {
+				if slices.ContainsFunc(env, func(variable string) bool {
+					return strings.HasPrefix(variable, name+"=")
+				}) {
+					t.Errorf("environ() = %v, want no %s", env, name)
+				}
+			}
+		})
+	}
+}
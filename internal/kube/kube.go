@@ -0,0 +1,157 @@
+package kube
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/RiskyFeryansyahP/paycast/internal/completion"
+	"github.com/RiskyFeryansyahP/paycast/internal/store"
+	"github.com/RiskyFeryansyahP/paycast/pkg/cmd"
+	"github.com/RiskyFeryansyahP/paycast/pkg/exit"
+	"github.com/RiskyFeryansyahP/paycast/pkg/logger"
+	"github.com/RiskyFeryansyahP/paycast/pkg/output"
+	"github.com/spf13/cobra"
+)
+
+var kubeCmd = &cobra.Command{
+	GroupID: "basic",
+	Use:     "kube",
+	Short:   "Manage Kubernetes access",
+	Long:    "List and log in to Kubernetes clusters with a kubeconfig private to the current context",
+}
+
+var kubeLsCmd = &cobra.Command{
+	Use:   "ls",
+	Short: "List Kubernetes clusters",
+	Long:  "List the Kubernetes clusters available to the current context",
+	Args:  cobra.NoArgs,
+	RunE:  kubeLsRun,
+}
+
+var kubeLoginCmd = &cobra.Command{
+	Use:               "login <kube-cluster>",
+	Short:             "Log in to a Kubernetes cluster",
+	Long:              "Write credentials for a Kubernetes cluster into the kubeconfig of the current context, it is refreshed on every relogin",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completion.First(completion.KubeClusters),
+	RunE:              kubeLoginRun,
+}
+
+var kubeEnvCmd = &cobra.Command{
+	Use:   "env",
+	Short: "Print shell exports for the context kubeconfig",
+	Long:  "Print the KUBECONFIG export of the current context, use with eval \"$(paycast kube env)\"",
+	Args:  cobra.NoArgs,
+	RunE:  kubeEnvRun,
+}
+
+func NewKubeCommand() *cobra.Command {
+	var unset bool
+
+	kubeEnvCmd.Flags().BoolVar(&unset, "unset", false, "Print commands to unset KUBECONFIG instead")
+
+	kubeCmd.AddCommand(kubeLsCmd, kubeLoginCmd, kubeEnvCmd)
+
+	return kubeCmd
+}
+
+func kubeLsRun(cobraCmd *cobra.Command, args []string) error {
+	ctx := cobraCmd.Context()
+
+	config, currentContext, err := store.LoadCurrent(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	configContext := config.Contexts[currentContext]
+
+	clusters, err := cmd.KubeClusters(ctx, configContext)
+
+	if err != nil {
+		return exit.Wrap(exit.Failure,
+			fmt.Errorf("failed to list Kubernetes clusters: %w", err),
+			"Check that the context is logged in with 'paycast login'")
+	}
+
+	result := make(ClustersResult, 0, len(clusters))
+
+	for _, cluster := range clusters {
+		result = append(result, ClusterEntry{
+			Name:     cluster.Name,
+			LoggedIn: slices.Contains(configContext.KubeClusters, cluster.Name),
+			Selected: cluster.Name == configContext.KubeCluster,
+		})
+	}
+
+	err = output.Print(result)
+
+	if err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+
+	return nil
+}
+
+func kubeLoginRun(cobraCmd *cobra.Command, args []string) error {
+	ctx := cobraCmd.Context()
+
+	config, currentContext, err := store.LoadCurrent(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	configContext := config.Contexts[currentContext]
+
+	cluster := args[0]
+
+	err = cmd.KubeLogin(ctx, configContext, cluster)
+
+	if err != nil {
+		return fmt.Errorf("failed to log in to Kubernetes cluster '%s': %w", cluster, err)
+	}
+
+	if !slices.Contains(configContext.KubeClusters, cluster) {
+		configContext.KubeClusters = append(configContext.KubeClusters, cluster)
+	}
+
+	configContext.KubeCluster = cluster
+	config.Contexts[currentContext] = configContext
+
+	err = store.Save(ctx, config)
+
+	if err != nil {
+		return fmt.Errorf("failed to save configuration file: %w", err)
+	}
+
+	logger.Info().
+		Str("context", currentContext).
+		Str("kube", cluster).
+		Str("kubeconfig", configContext.Kubeconfig()).
+		Msg("Logged in to Kubernetes cluster, run 'eval \"$(paycast kube env)\"' to use it")
+
+	return nil
+}
+
+func kubeEnvRun(cobraCmd *cobra.Command, args []string) error {
+	unset, _ := cobraCmd.Flags().GetBool("unset")
+
+	if unset {
+		fmt.Println("unset KUBECONFIG")
+
+		return nil
+	}
+
+	config, currentContext, err := store.LoadCurrent(cobraCmd.Context())
+
+	if err != nil {
+		return err
+	}
+
+	configContext := config.Contexts[currentContext]
+
+	fmt.Printf("export KUBECONFIG=%q\n", configContext.Kubeconfig())
+
+	return nil
+}
@@ -0,0 +1,28 @@
+package kube
+
+import "strconv"
+
+// ClustersResult is the output of 'kube ls'
+type ClustersResult []ClusterEntry
+
+// ClusterEntry is a Kubernetes cluster of the context, LoggedIn when it has
+// been logged in with 'paycast kube login'
+type ClusterEntry struct {
+	Name     string `json:"name"`
+	LoggedIn bool   `json:"logged_in"`
+	Selected bool   `json:"selected"`
+}
+
+func (r ClustersResult) Header() []string {
+	return []string{"NAME", "LOGGED IN", "SELECTED"}
+}
+
+func (r ClustersResult) Rows() [][]string {
+	rows := make([][]string, 0, len(r))
+
+	for _, cluster := range r {
+		rows = append(rows, []string{cluster.Name, strconv.FormatBool(cluster.LoggedIn), strconv.FormatBool(cluster.Selected)})
+	}
+
+	return rows
+}
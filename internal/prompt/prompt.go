@@ -0,0 +1,166 @@
+package prompt
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/RiskyFeryansyahP/paycast/internal/status"
+	"github.com/RiskyFeryansyahP/paycast/internal/store"
+	"github.com/RiskyFeryansyahP/paycast/pkg/exit"
+	"github.com/spf13/cobra"
+)
+
+// FORMAT_ENV overrides the default template, so prompts can be configured
+// once in the shell profile
+const FORMAT_ENV = "PAYCAST_PROMPT_FORMAT"
+
+// DefaultFormat prints e.g. "[staging/leaf 3h12m]"
+const DefaultFormat = "{{.Color}}[{{.Context}}{{if .Cluster}}/{{.Cluster}}{{end}} {{.TimeLeft}}]{{.Reset}}"
+
+// ANSI colors of the environments, anything else is cyan
+const (
+	colorRed    = "\033[31m"
+	colorYellow = "\033[33m"
+	colorGreen  = "\033[32m"
+	colorCyan   = "\033[36m"
+	colorReset  = "\033[0m"
+)
+
+var promptCmd = &cobra.Command{
+	GroupID: "basic",
+	Use:     "prompt",
+	Short:   "Print a prompt segment for the active context",
+	Long: "Print the active context, cluster and remaining session time for shell prompts. It only reads " +
+		"the paycast configuration and never runs tsh, and prints nothing when there is no active context. " +
+		"The segment is a Go template over .Context, .Cluster, .User, .Environment, .TimeLeft, .Expired, " +
+		".Color and .Reset, set with --format or " + FORMAT_ENV,
+	Args: cobra.NoArgs,
+	RunE: promptRun,
+}
+
+// Segment is the data the prompt template is executed with
+type Segment struct {
+	Context     string
+	Cluster     string
+	User        string
+	Environment string
+	TimeLeft    string
+	Expired     bool
+	Color       string
+	Reset       string
+}
+
+func NewPromptCommand() *cobra.Command {
+	var format, shell string
+	var noColor bool
+
+	promptCmd.Flags().StringVar(&format, "format", "", "Template of the segment, defaults to "+FORMAT_ENV+" or the built-in format")
+	promptCmd.Flags().StringVar(&shell, "shell", "", "Wrap colors for the prompt of bash or zsh, empty prints them as is")
+	promptCmd.Flags().BoolVar(&noColor, "no-color", false, "Leave colors out of the segment")
+	_ = promptCmd.RegisterFlagCompletionFunc("shell", cobra.FixedCompletions([]string{"bash", "zsh"}, cobra.ShellCompDirectiveNoFileComp))
+
+	return promptCmd
+}
+
+func promptRun(cobraCmd *cobra.Command, args []string) error {
+	ctx := cobraCmd.Context()
+
+	format := cobraCmd.Flag("format").Value.String()
+	shell := cobraCmd.Flag("shell").Value.String()
+	noColor, _ := cobraCmd.Flags().GetBool("no-color")
+
+	if shell != "" && shell != "bash" && shell != "zsh" {
+		return exit.New(exit.Usage, fmt.Errorf("unknown shell '%s'", shell), "Use one of bash or zsh")
+	}
+
+	if format == "" {
+		format = os.Getenv(FORMAT_ENV)
+	}
+
+	if format == "" {
+		format = DefaultFormat
+	}
+
+	tmpl, err := template.New("prompt").Parse(format)
+
+	if err != nil {
+		return exit.New(exit.Usage, fmt.Errorf("invalid prompt format: %w", err), "")
+	}
+
+	// A prompt renders on every command, so a missing or broken
+	// configuration prints nothing instead of complaining each time
+	config, err := store.Get(ctx)
+
+	if err != nil {
+		return nil
+	}
+
+	configContext, ok := config.Contexts[config.Active()]
+
+	if !ok {
+		return nil
+	}
+
+	segment := NewSegment(configContext, time.Now())
+
+	if !noColor {
+		segment.Color = wrap(shell, segment.Color)
+		segment.Reset = wrap(shell, colorReset)
+	} else {
+		segment.Color = ""
+	}
+
+	err = tmpl.Execute(os.Stdout, segment)
+
+	if err != nil {
+		return fmt.Errorf("failed to render prompt format: %w", err)
+	}
+
+	return nil
+}
+
+// NewSegment describes the context at now, Color is the raw color sequence
+// of its environment and Reset is left empty
+func NewSegment(configContext store.Context, now time.Time) Segment {
+	return Segment{
+		Context:     configContext.Name,
+		Cluster:     configContext.Cluster,
+		User:        configContext.User,
+		Environment: configContext.Environment,
+		TimeLeft:    status.Humanize(configContext.Expiry.Sub(now)),
+		Expired:     !now.Before(configContext.Expiry),
+		Color:       Color(configContext.Environment),
+	}
+}
+
+// Color returns the ANSI color of an environment label
+func Color(environment string) string {
+	switch strings.ToLower(environment) {
+	case "prod", "production", "live":
+		return colorRed
+	case "stg", "staging", "uat", "preprod":
+		return colorYellow
+	case "dev", "development", "local", "sandbox":
+		return colorGreen
+	}
+
+	return colorCyan
+}
+
+// wrap marks an escape sequence as zero width so the shell computes the
+// prompt length correctly. Bash decodes \[ and \] before it runs command
+// substitutions, so output of $(paycast prompt) needs the raw \001 and \002
+// markers they stand for
+func wrap(shell, sequence string) string {
+	switch shell {
+	case "bash":
+		return "\001" + sequence + "\002"
+	case "zsh":
+		return "%{" + sequence + "%}"
+	}
+
+	return sequence
+}
@@ -0,0 +1,62 @@
+package prompt
+
+import (
+	"testing"
+	"time"
+
+	"github.com/RiskyFeryansyahP/paycast/internal/store"
+)
+
+func TestWrap(t *testing.T) {
+	tests := []struct {
+		shell string
+		want  string
+	}{
+		{shell: "bash", want: "\001" + colorRed + "\002"},
+		{shell: "zsh", want: "%{" + colorRed + "%}"},
+		{shell: "", want: colorRed},
+	}
+
+	for _, tt := range tests {
+		got := wrap(tt.shell, colorRed)
+
+		if got != tt.want {
+			t.Errorf("wrap(%q) = %q, want %q", tt.shell, got, tt.want)
+		}
+	}
+}
+
+func TestColor(t *testing.T) {
+	tests := []struct {
+		environment string
+		want        string
+	}{
+		{environment: "Production", want: colorRed},
+		{environment: "staging", want: colorYellow},
+		{environment: "dev", want: colorGreen},
+		{environment: "", want: colorCyan},
+	}
+
+	for _, tt := range tests {
+		got := Color(tt.environment)
+
+		if got != tt.want {
+			t.Errorf("Color(%q) = %q, want %q", tt.environment, got, tt.want)
+		}
+	}
+}
+
+func TestNewSegment(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	segment := NewSegment(store.Context{
+		Name:        "staging",
+		Cluster:     "leaf",
+		Environment: "staging",
+		Expiry:      now.Add(-time.Minute),
+	}, now)
+
+	if segment.Context != "staging" || segment.Cluster != "leaf" || !segment.Expired || segment.Color != colorYellow {
+		t.Errorf("NewSegment() = %+v", segment)
+	}
+}
@@ -0,0 +1,329 @@
+package request
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/RiskyFeryansyahP/paycast/internal/completion"
+	"github.com/RiskyFeryansyahP/paycast/internal/store"
+	"github.com/RiskyFeryansyahP/paycast/pkg/cmd"
+	"github.com/RiskyFeryansyahP/paycast/pkg/dryrun"
+	"github.com/RiskyFeryansyahP/paycast/pkg/exit"
+	"github.com/RiskyFeryansyahP/paycast/pkg/logger"
+	"github.com/spf13/cobra"
+)
+
+// ErrRequestDenied is returned when an access request was not approved
+var ErrRequestDenied = errors.New("access request was denied")
+
+var requestIDPattern = regexp.MustCompile(`(?i)request id:\s*([0-9a-f-]{36})`)
+
+var requestCmd = &cobra.Command{
+	GroupID: "basic",
+	Use:     "request",
+	Short:   "Manage Teleport access requests",
+	Long:    "Create access requests for additional roles and log in with them once approved",
+}
+
+var requestCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Create an access request",
+	Long:  "Request additional roles for the current context, the request ID is remembered until it is resolved",
+	Args:  cobra.NoArgs,
+	RunE:  requestCreateRun,
+}
+
+var requestWaitCmd = &cobra.Command{
+	Use:               "wait [request-id]",
+	Short:             "Wait for an access request to be resolved",
+	Long:              "Poll an access request until it is approved or denied and log in with the approved roles, defaults to the last pending request",
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: completion.First(completion.PendingRequests),
+	RunE:              requestWaitRun,
+}
+
+func NewRequestCommand() *cobra.Command {
+	var roles []string
+	var reason string
+	var timeout, interval time.Duration
+
+	requestCreateCmd.Flags().StringSliceVar(&roles, "roles", nil, "Roles to request")
+	requestCreateCmd.Flags().StringVar(&reason, "reason", "", "Reason for the request shown to reviewers")
+	_ = requestCreateCmd.MarkFlagRequired("roles")
+	_ = requestCreateCmd.MarkFlagRequired("reason")
+
+	requestWaitCmd.Flags().DurationVar(&timeout, "timeout", 30*time.Minute, "How long to wait for the request to be resolved")
+	requestWaitCmd.Flags().DurationVar(&interval, "interval", 10*time.Second, "How often to check the request state")
+
+	requestCmd.AddCommand(requestCreateCmd, requestWaitCmd)
+
+	return requestCmd
+}
+
+func requestCreateRun(cobraCmd *cobra.Command, args []string) error {
+	ctx := cobraCmd.Context()
+
+	roles, _ := cobraCmd.Flags().GetStringSlice("roles")
+	reason := cobraCmd.Flag("reason").Value.String()
+
+	config, currentContext, err := store.LoadCurrent(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	configContext := config.Contexts[currentContext]
+
+	output, err := cmd.Command(ctx, configContext.TeleportHome(), "request", "create",
+		fmt.Sprintf("--roles=%s", strings.Join(roles, ",")),
+		fmt.Sprintf("--reason=%s", reason),
+		"--nowait",
+	).CombinedOutput()
+
+	if err != nil {
+		return fmt.Errorf("tsh request create failed: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+
+	// Without tsh output there is no request ID to remember
+	if dryrun.Enabled() {
+		logger.Info().
+			Str("context", currentContext).
+			Strs("roles", roles).
+			Msg("Dry run, no access request created")
+
+		return nil
+	}
+
+	match := requestIDPattern.FindStringSubmatch(cmd.CleanOutput(string(output)))
+
+	if match == nil {
+		return fmt.Errorf("failed to find the request ID in tsh output: %s", strings.TrimSpace(string(output)))
+	}
+
+	requestID := match[1]
+
+	configContext.PendingRequests = append(configContext.PendingRequests, requestID)
+	config.Contexts[currentContext] = configContext
+
+	err = store.Save(ctx, config)
+
+	if err != nil {
+		return fmt.Errorf("failed to save configuration file: %w", err)
+	}
+
+	logger.Info().
+		Str("context", currentContext).
+		Str("request", requestID).
+		Strs("roles", roles).
+		Msg("Access request created, run 'paycast request wait' to log in once approved")
+
+	return nil
+}
+
+func requestWaitRun(cobraCmd *cobra.Command, args []string) error {
+	ctx := cobraCmd.Context()
+
+	timeout, _ := cobraCmd.Flags().GetDuration("timeout")
+	interval, _ := cobraCmd.Flags().GetDuration("interval")
+
+	config, currentContext, err := store.LoadCurrent(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	configContext := config.Contexts[currentContext]
+
+	var requestID string
+
+	if len(args) > 0 {
+		requestID = args[0]
+	} else if len(configContext.PendingRequests) > 0 {
+		requestID = configContext.PendingRequests[len(configContext.PendingRequests)-1]
+	} else {
+		return exit.New(exit.Failure,
+			fmt.Errorf("no pending access request in context '%s'", currentContext),
+			"Run 'paycast request create' first")
+	}
+
+	logger.Info().
+		Str("request", requestID).
+		Msg("Waiting for access request to be reviewed")
+
+	deadline := time.Now().Add(timeout)
+	var request *AccessRequest
+
+	for {
+		var err error
+
+		request, err = Show(ctx, configContext, requestID)
+
+		if err != nil {
+			return fmt.Errorf("failed to read access request '%s': %w", requestID, err)
+		}
+
+		if request.State != StatePending {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			return exit.New(exit.Failure,
+				fmt.Errorf("timed out waiting for access request '%s' to be reviewed", requestID),
+				"Run 'paycast request wait' again to keep waiting")
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+
+	configContext.PendingRequests = slices.DeleteFunc(configContext.PendingRequests, func(id string) bool {
+		return id == requestID
+	})
+
+	if request.State != StateApproved {
+		config.Contexts[currentContext] = configContext
+		_ = store.Save(ctx, config)
+
+		return fmt.Errorf("%w: request '%s' is %s", ErrRequestDenied, requestID, request.State)
+	}
+
+	if !slices.Contains(configContext.RequestIDs, requestID) {
+		configContext.RequestIDs = append(configContext.RequestIDs, requestID)
+	}
+
+	if !request.Expires.IsZero() {
+		if configContext.RequestExpiry == nil {
+			configContext.RequestExpiry = make(map[string]time.Time)
+		}
+
+		configContext.RequestExpiry[requestID] = request.Expires.UTC()
+	}
+
+	updatedConfigContext, err := cmd.Relogin(ctx, &configContext)
+
+	if err != nil {
+		return fmt.Errorf("failed to login with approved access request '%s': %w", requestID, err)
+	}
+
+	config.Contexts[currentContext] = *updatedConfigContext
+
+	err = store.Save(ctx, config)
+
+	if err != nil {
+		return fmt.Errorf("failed to save configuration file: %w", err)
+	}
+
+	logger.Info().
+		Str("context", currentContext).
+		Str("request", requestID).
+		Strs("roles", updatedConfigContext.Roles).
+		Msg(dryrun.Message("Access request approved, logged in with requested roles", "Dry run, access request approved, would log in with requested roles"))
+
+	return nil
+}
+
+// RequestState is the review state of an access request
+type RequestState int
+
+const (
+	StateNone RequestState = iota
+	StatePending
+	StateApproved
+	StateDenied
+	StatePromoted
+)
+
+var stateNames = []string{"NONE", "PENDING", "APPROVED", "DENIED", "PROMOTED"}
+
+func (s RequestState) String() string {
+	if int(s) < len(stateNames) {
+		return stateNames[s]
+	}
+
+	return fmt.Sprintf("STATE(%d)", int(s))
+}
+
+// UnmarshalJSON accepts the state both as enum number and as name
+func (s *RequestState) UnmarshalJSON(data []byte) error {
+	var number int
+
+	if json.Unmarshal(data, &number) == nil {
+		*s = RequestState(number)
+		return nil
+	}
+
+	var name string
+
+	err := json.Unmarshal(data, &name)
+
+	if err != nil {
+		return err
+	}
+
+	index := slices.Index(stateNames, strings.ToUpper(name))
+
+	if index < 0 {
+		return fmt.Errorf("unknown access request state '%s'", name)
+	}
+
+	*s = RequestState(index)
+
+	return nil
+}
+
+// AccessRequest is the part of `tsh request show --format=json` paycast uses.
+// Expires is when the access granted by the request ends
+type AccessRequest struct {
+	ID      string
+	Roles   []string
+	State   RequestState
+	Expires time.Time
+}
+
+// Show reads the state of an access request
+func Show(ctx context.Context, configContext store.Context, requestID string) (*AccessRequest, error) {
+	output, err := cmd.Command(ctx, configContext.TeleportHome(), "request", "show", requestID, "--format=json").Output()
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Report the request approved so a dry run goes on to show the login
+	// that follows approval
+	if dryrun.Enabled() {
+		return &AccessRequest{ID: requestID, State: StateApproved}, nil
+	}
+
+	var document struct {
+		Metadata struct {
+			Name string `json:"name"`
+		} `json:"metadata"`
+		Spec struct {
+			Roles   []string     `json:"roles"`
+			State   RequestState `json:"state"`
+			Expires time.Time    `json:"expires"`
+		} `json:"spec"`
+	}
+
+	err = json.Unmarshal(output, &document)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &AccessRequest{
+		ID:      document.Metadata.Name,
+		Roles:   document.Spec.Roles,
+		State:   document.Spec.State,
+		Expires: document.Spec.Expires,
+	}, nil
+}
@@ -0,0 +1,219 @@
+package shell
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/RiskyFeryansyahP/paycast/internal/completion"
+	"github.com/RiskyFeryansyahP/paycast/internal/store"
+	"github.com/RiskyFeryansyahP/paycast/internal/supervisor"
+	"github.com/RiskyFeryansyahP/paycast/internal/workspace"
+	"github.com/RiskyFeryansyahP/paycast/pkg/cmd"
+	"github.com/RiskyFeryansyahP/paycast/pkg/exit"
+	"github.com/RiskyFeryansyahP/paycast/pkg/logger"
+	"github.com/spf13/cobra"
+)
+
+// PROMPT_ENV holds a marker for the prompt of a paycast shell, e.g.
+// PS1="$PAYCAST_PROMPT$PS1"
+const PROMPT_ENV = "PAYCAST_PROMPT"
+
+var shellCmd = &cobra.Command{
+	GroupID: "basic",
+	Use:     "shell [-- shell args...]",
+	Short:   "Start a shell scoped to a context",
+	Long: "Start $SHELL with PAYCAST_CONTEXT, TELEPORT_HOME, KUBECONFIG and PAYCAST_DB_* variables of a " +
+		"context, so paycast and tsh in that shell use the context whatever the current context is. " +
+		"The prompt marker is exported as PAYCAST_PROMPT, selected proxies run until the shell exits",
+	Args: cobra.ArbitraryArgs,
+	RunE: shellRun,
+}
+
+func NewShellCommand() *cobra.Command {
+	var contextName, workspaceName string
+	var dbs, apps, tunnels []string
+
+	shellCmd.Flags().StringVar(&contextName, "context", "", "Context of the shell, defaults to the current context")
+	shellCmd.Flags().StringArrayVar(&dbs, "db", nil, "Database tunnel to run while the shell is open, repeatable")
+	shellCmd.Flags().StringArrayVar(&apps, "app", nil, "Application to run while the shell is open, repeatable")
+	shellCmd.Flags().StringArrayVar(&tunnels, "tunnel", nil, "SSH host alias whose forwards to run while the shell is open, repeatable")
+	shellCmd.Flags().StringVar(&workspaceName, "workspace", "", "Workspace to run while the shell is open")
+	_ = shellCmd.RegisterFlagCompletionFunc("context", completion.Contexts)
+	_ = shellCmd.RegisterFlagCompletionFunc("db", completion.Databases)
+	_ = shellCmd.RegisterFlagCompletionFunc("app", completion.Apps)
+	_ = shellCmd.RegisterFlagCompletionFunc("tunnel", completion.Hosts)
+	_ = shellCmd.RegisterFlagCompletionFunc("workspace", completion.Workspaces)
+
+	return shellCmd
+}
+
+func shellRun(cobraCmd *cobra.Command, args []string) error {
+	ctx := cobraCmd.Context()
+
+	contextName := cobraCmd.Flag("context").Value.String()
+	workspaceName := cobraCmd.Flag("workspace").Value.String()
+	dbs, _ := cobraCmd.Flags().GetStringArray("db")
+	apps, _ := cobraCmd.Flags().GetStringArray("app")
+	tunnels, _ := cobraCmd.Flags().GetStringArray("tunnel")
+
+	config, err := store.Load(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	if contextName == "" {
+		contextName = config.Active()
+	}
+
+	if contextName == "" {
+		return store.ErrNoContext
+	}
+
+	configContext, ok := config.Contexts[contextName]
+
+	if !ok {
+		return exit.New(exit.ContextMissing,
+			fmt.Errorf("context '%s' not found", contextName),
+			"Run 'paycast status' to see contexts")
+	}
+
+	if !configContext.Isolated {
+		logger.Warn().
+			Str("context", contextName).
+			Msg("Context is not isolated, tsh in this shell shares its session with other terminals")
+	}
+
+	members := store.Workspace{
+		Name:      workspaceName,
+		Databases: dbs,
+		Apps:      apps,
+		Tunnels:   tunnels,
+	}
+
+	if workspaceName != "" {
+		selected, ok := configContext.Workspaces[workspaceName]
+
+		if !ok {
+			return exit.New(exit.Failure,
+				fmt.Errorf("workspace '%s' not found", workspaceName),
+				"Run 'paycast workspace list' to see workspaces")
+		}
+
+		members.Databases = append(members.Databases, selected.Databases...)
+		members.Apps = append(members.Apps, selected.Apps...)
+		members.Tunnels = append(members.Tunnels, selected.Tunnels...)
+	}
+
+	proxies, err := workspace.Proxies(configContext, members)
+
+	if err != nil {
+		return err
+	}
+
+	// Proxies outlive neither the shell nor an error starting it
+	proxyCtx, stopProxies := context.WithCancel(ctx)
+	defer stopProxies()
+
+	var done <-chan error
+
+	if len(proxies) > 0 {
+		done, err = supervisor.Start(proxyCtx, config, contextName, proxies)
+
+		if err != nil {
+			return fmt.Errorf("failed to start proxies of context '%s': %w", contextName, err)
+		}
+
+		// The session may have been renewed while starting the proxies
+		config, _ = store.Get(ctx)
+		configContext = config.Contexts[contextName]
+	} else if time.Now().After(configContext.Expiry) {
+		logger.Warn().
+			Str("context", contextName).
+			Msg("Session expired, run 'paycast login' in the shell to renew it")
+	}
+
+	program := os.Getenv("SHELL")
+
+	if program == "" {
+		program = "/bin/sh"
+	}
+
+	shell := exec.CommandContext(ctx, program, args...)
+	shell.Env = append(os.Environ(), Environ(configContext, proxies)...)
+
+	logger.Info().
+		Str("context", contextName).
+		Str("shell", program).
+		Int("proxies", len(proxies)).
+		Msg("Starting shell, exit it to leave the context")
+
+	err = cmd.RunInteractive(shell)
+
+	stopProxies()
+
+	if done != nil {
+		<-done
+	}
+
+	var exitErr *exec.ExitError
+
+	// The exit status of the shell is the user's, not a paycast failure
+	if errors.As(err, &exitErr) && exitErr.ExitCode() > 0 {
+		return exit.Status(exit.Code(exitErr.ExitCode()), err)
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to run shell '%s': %w", program, err)
+	}
+
+	return nil
+}
+
+// Environ returns the variables scoping a shell to the context. Variables of
+// the context databases are named after their tunnel, PAYCAST_DB_<TUNNEL>_PORT
+// and so on, and marked running when the database is among proxies
+func Environ(configContext store.Context, proxies []supervisor.Proxy) []string {
+	env := []string{
+		store.CONTEXT_ENV + "=" + configContext.Name,
+		"KUBECONFIG=" + configContext.Kubeconfig(),
+		PROMPT_ENV + "=(" + configContext.Name + ") ",
+	}
+
+	if home := configContext.TeleportHome(); home != "" {
+		env = append(env, "TELEPORT_HOME="+home)
+	}
+
+	tunnels := make([]string, 0, len(configContext.Database))
+
+	for tunnel := range configContext.Database {
+		tunnels = append(tunnels, tunnel)
+	}
+
+	slices.Sort(tunnels)
+
+	for _, tunnel := range tunnels {
+		db := configContext.Database[tunnel]
+		prefix := "PAYCAST_DB_" + cmd.EnvName(tunnel) + "_"
+
+		running := slices.ContainsFunc(proxies, func(proxy supervisor.Proxy) bool {
+			return proxy.Kind == "db" && proxy.Name == tunnel
+		})
+
+		env = append(env,
+			prefix+"HOST=localhost",
+			prefix+"PORT="+strconv.Itoa(int(db.Port)),
+			prefix+"USER="+db.User,
+			prefix+"NAME="+db.Name,
+			prefix+"RUNNING="+strconv.FormatBool(running),
+		)
+	}
+
+	return env
+}
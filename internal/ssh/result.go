@@ -0,0 +1,50 @@
+package ssh
+
+import (
+	"strings"
+
+	"github.com/RiskyFeryansyahP/paycast/internal/store"
+)
+
+// HostsResult is the output of 'ssh list'
+type HostsResult []HostEntry
+
+// HostEntry is an SSH bookmark of the context, forwards are written as
+// <local port>:<remote host>:<remote port>
+type HostEntry struct {
+	Alias    string   `json:"alias"`
+	Login    string   `json:"login"`
+	Host     string   `json:"host"`
+	Cluster  string   `json:"cluster,omitempty"`
+	Forwards []string `json:"forwards,omitempty"`
+}
+
+func newHostEntry(host store.Host) HostEntry {
+	forwards := make([]string, 0, len(host.Forwards))
+
+	for _, forward := range host.Forwards {
+		forwards = append(forwards, forward.String())
+	}
+
+	return HostEntry{
+		Alias:    host.Alias,
+		Login:    host.Login,
+		Host:     host.Host,
+		Cluster:  host.Cluster,
+		Forwards: forwards,
+	}
+}
+
+func (r HostsResult) Header() []string {
+	return []string{"ALIAS", "LOGIN", "HOST", "CLUSTER", "FORWARDS"}
+}
+
+func (r HostsResult) Rows() [][]string {
+	rows := make([][]string, 0, len(r))
+
+	for _, host := range r {
+		rows = append(rows, []string{host.Alias, host.Login, host.Host, host.Cluster, strings.Join(host.Forwards, ",")})
+	}
+
+	return rows
+}
@@ -0,0 +1,357 @@
+package ssh
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/RiskyFeryansyahP/paycast/internal/completion"
+	"github.com/RiskyFeryansyahP/paycast/internal/store"
+	"github.com/RiskyFeryansyahP/paycast/internal/supervisor"
+	"github.com/RiskyFeryansyahP/paycast/pkg/cmd"
+	"github.com/RiskyFeryansyahP/paycast/pkg/dryrun"
+	"github.com/RiskyFeryansyahP/paycast/pkg/exit"
+	"github.com/RiskyFeryansyahP/paycast/pkg/logger"
+	"github.com/RiskyFeryansyahP/paycast/pkg/output"
+	"github.com/spf13/cobra"
+)
+
+var sshCmd = &cobra.Command{
+	GroupID: "basic",
+	Use:     "ssh <alias> [command...]",
+	Short:   "Connect to bookmarked SSH hosts",
+	Long: "Open an interactive session on a bookmarked host of the current context, with its port forwards. " +
+		"Arguments after the alias, flags included, are the remote command",
+	Args:              cobra.MinimumNArgs(1),
+	ValidArgsFunction: completion.First(completion.Hosts),
+	RunE:              sshRun,
+}
+
+var sshAddCmd = &cobra.Command{
+	Use:   "add <alias>",
+	Short: "Add an SSH host bookmark",
+	Long:  "Bookmark a host of the current context together with the ports to forward through it",
+	Args:  cobra.ExactArgs(1),
+	RunE:  sshAddRun,
+}
+
+var sshListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List SSH host bookmarks",
+	Long:  "List the SSH host bookmarks of the current context",
+	Args:  cobra.NoArgs,
+	RunE:  sshListRun,
+}
+
+var sshDeleteCmd = &cobra.Command{
+	Use:               "delete <alias>",
+	Short:             "Remove an SSH host bookmark",
+	Long:              "Delete the specified SSH host bookmark from the current context",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completion.First(completion.Hosts),
+	RunE:              sshDeleteRun,
+}
+
+var sshTunnelCmd = &cobra.Command{
+	Use:               "tunnel [alias...]",
+	Short:             "Run port forwards without a shell",
+	Long:              "Keep the port forwards of the given bookmarks open without a session, defaults to every bookmark with forwards",
+	ValidArgsFunction: completion.Hosts,
+	RunE:              sshTunnelRun,
+}
+
+func NewSSHCommand() *cobra.Command {
+	var login, host, cluster string
+	var forwards []string
+
+	sshAddCmd.Flags().StringVarP(&login, "login", "l", "", "Remote login to use")
+	sshAddCmd.Flags().StringVarP(&host, "host", "H", "", "Teleport node to connect to")
+	sshAddCmd.Flags().StringVarP(&cluster, "cluster", "c", "", "Leaf cluster the host is reached through, defaults to the root cluster")
+	sshAddCmd.Flags().StringArrayVarP(&forwards, "forward", "L", nil, "Forward local_port:remote_host:remote_port through the host")
+	_ = sshAddCmd.RegisterFlagCompletionFunc("login", completion.Logins)
+	_ = sshAddCmd.RegisterFlagCompletionFunc("cluster", completion.LeafClusters)
+	_ = sshAddCmd.MarkFlagRequired("login")
+	_ = sshAddCmd.MarkFlagRequired("host")
+
+	// Flags after the alias belong to the remote command, e.g. 'ls -la'
+	sshCmd.Flags().SetInterspersed(false)
+
+	sshCmd.AddCommand(sshAddCmd, sshDeleteCmd, sshListCmd, sshTunnelCmd)
+
+	return sshCmd
+}
+
+func sshRun(cobraCmd *cobra.Command, args []string) error {
+	ctx := cobraCmd.Context()
+
+	config, currentContext, err := store.LoadCurrent(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	host, err := findHost(config.Contexts[currentContext], args[0])
+
+	if err != nil {
+		return err
+	}
+
+	configContext, err := supervisor.EnsureSession(ctx, config, currentContext)
+
+	if err != nil {
+		return fmt.Errorf("failed to relogin to set context when context expired: %w", err)
+	}
+
+	sshArgs := append(Args(host, false), args[1:]...)
+
+	err = cmd.RunInteractive(cmd.Command(ctx, configContext.TeleportHome(), sshArgs...))
+
+	if err != nil {
+		return fmt.Errorf("ssh session to '%s' ended with error: %w", host.Alias, err)
+	}
+
+	return nil
+}
+
+func sshAddRun(cobraCmd *cobra.Command, args []string) error {
+	ctx := cobraCmd.Context()
+
+	login := cobraCmd.Flag("login").Value.String()
+	hostName := cobraCmd.Flag("host").Value.String()
+	cluster := cobraCmd.Flag("cluster").Value.String()
+	forwardValues, _ := cobraCmd.Flags().GetStringArray("forward")
+
+	config, currentContext, err := store.LoadCurrent(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	configContext := config.Contexts[currentContext]
+
+	alias := args[0]
+
+	cluster, err = configContext.LeafCluster(cluster)
+
+	if err != nil {
+		return err
+	}
+
+	forwards := make([]store.Forward, 0, len(forwardValues))
+
+	for _, value := range forwardValues {
+		forward, err := store.ParseForward(value)
+
+		if err != nil {
+			return exit.New(exit.Usage, err, "")
+		}
+
+		owner, taken := configContext.PortOwner(forward.LocalPort)
+
+		if taken && owner != "ssh/"+alias {
+			return exit.New(exit.PortInUse,
+				fmt.Errorf("port %d is already used by %s", forward.LocalPort, owner),
+				"Choose a different port")
+		}
+
+		forwards = append(forwards, forward)
+	}
+
+	if len(configContext.Hosts) == 0 {
+		configContext.Hosts = make(map[string]store.Host)
+	}
+
+	configContext.Hosts[alias] = store.Host{
+		Alias:    alias,
+		Login:    login,
+		Host:     hostName,
+		Cluster:  cluster,
+		Forwards: forwards,
+	}
+	config.Contexts[currentContext] = configContext
+
+	err = store.Save(ctx, config)
+
+	if err != nil {
+		return fmt.Errorf("failed to save configuration file: %w", err)
+	}
+
+	logger.Info().
+		Str("ssh", alias).
+		Str("host", fmt.Sprintf("%s@%s", login, hostName)).
+		Int("forwards", len(forwards)).
+		Msg(dryrun.Message("SSH host added successfully", "Dry run, SSH host would be added"))
+
+	return nil
+}
+
+func sshDeleteRun(cobraCmd *cobra.Command, args []string) error {
+	ctx := cobraCmd.Context()
+
+	config, currentContext, err := store.LoadCurrent(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	configContext := config.Contexts[currentContext]
+
+	alias := args[0]
+
+	_, err = findHost(configContext, alias)
+
+	if err != nil {
+		return err
+	}
+
+	if workspace, ok := configContext.WorkspaceUsing("ssh/" + alias); ok {
+		return exit.New(exit.Failure,
+			fmt.Errorf("ssh host '%s' is used by workspace '%s'", alias, workspace),
+			"Update the workspace with 'paycast workspace create' or delete it first")
+	}
+
+	delete(configContext.Hosts, alias)
+	config.Contexts[currentContext] = configContext
+
+	err = store.Save(ctx, config)
+
+	if err != nil {
+		return fmt.Errorf("failed to save configuration file: %w", err)
+	}
+
+	logger.Info().
+		Str("ssh", alias).
+		Msg(dryrun.Message("SSH host deleted successfully", "Dry run, SSH host would be deleted"))
+
+	return nil
+}
+
+func sshListRun(cobraCmd *cobra.Command, args []string) error {
+	config, currentContext, err := store.LoadCurrent(cobraCmd.Context())
+
+	if err != nil {
+		return err
+	}
+
+	configContext := config.Contexts[currentContext]
+
+	aliases := make([]string, 0, len(configContext.Hosts))
+
+	for alias := range configContext.Hosts {
+		aliases = append(aliases, alias)
+	}
+
+	slices.Sort(aliases)
+
+	result := make(HostsResult, 0, len(aliases))
+
+	for _, alias := range aliases {
+		result = append(result, newHostEntry(configContext.Hosts[alias]))
+	}
+
+	err = output.Print(result)
+
+	if err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+
+	return nil
+}
+
+func sshTunnelRun(cobraCmd *cobra.Command, args []string) error {
+	ctx := cobraCmd.Context()
+
+	config, currentContext, err := store.LoadCurrent(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	configContext := config.Contexts[currentContext]
+
+	aliases := args
+
+	if len(aliases) == 0 {
+		for alias, host := range configContext.Hosts {
+			if len(host.Forwards) > 0 {
+				aliases = append(aliases, alias)
+			}
+		}
+	}
+
+	proxies := make([]supervisor.Proxy, 0, len(aliases))
+
+	for _, alias := range aliases {
+		host, err := findHost(configContext, alias)
+
+		if err != nil {
+			return err
+		}
+
+		if len(host.Forwards) == 0 {
+			return exit.New(exit.Failure,
+				fmt.Errorf("ssh host '%s' has no port forwards", alias),
+				"Add forwards with 'paycast ssh add --forward'")
+		}
+
+		proxies = append(proxies, Proxy(host))
+	}
+
+	err = supervisor.Run(ctx, config, currentContext, proxies)
+
+	if err != nil {
+		return fmt.Errorf("failed to run SSH tunnels: %w", err)
+	}
+
+	return nil
+}
+
+// Args builds the tsh ssh command line of a bookmark. A forward only session
+// keeps the forwards open without running a shell
+func Args(host store.Host, forwardOnly bool) []string {
+	args := []string{"ssh"}
+
+	if host.Cluster != "" {
+		args = append(args, fmt.Sprintf("--cluster=%s", host.Cluster))
+	}
+
+	for _, forward := range host.Forwards {
+		args = append(args, "-L", forward.String())
+	}
+
+	if forwardOnly {
+		args = append(args, "-N")
+	}
+
+	return append(args, fmt.Sprintf("%s@%s", host.Login, host.Host))
+}
+
+// Proxy describes the forward only tsh ssh session of a bookmark
+func Proxy(host store.Host) supervisor.Proxy {
+	ports := make([]int32, 0, len(host.Forwards))
+
+	for _, forward := range host.Forwards {
+		ports = append(ports, forward.LocalPort)
+	}
+
+	return supervisor.Proxy{
+		Kind:  "ssh",
+		Name:  host.Alias,
+		Ports: ports,
+		Args:  Args(host, true),
+		Fields: map[string]string{
+			"node": fmt.Sprintf("%s@%s", host.Login, host.Host),
+		},
+	}
+}
+
+func findHost(configContext store.Context, alias string) (store.Host, error) {
+	host, ok := configContext.Hosts[alias]
+
+	if !ok {
+		return store.Host{}, exit.New(exit.Failure,
+			fmt.Errorf("ssh host '%s' not found", alias),
+			"Run 'paycast ssh list' to see bookmarked hosts")
+	}
+
+	return host, nil
+}
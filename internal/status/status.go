@@ -0,0 +1,196 @@
+package status
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"slices"
+	"sort"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/RiskyFeryansyahP/paycast/internal/store"
+	"github.com/RiskyFeryansyahP/paycast/pkg/cmd"
+	"github.com/RiskyFeryansyahP/paycast/pkg/logger"
+	"github.com/RiskyFeryansyahP/paycast/pkg/output"
+	"github.com/spf13/cobra"
+)
+
+var statusCmd = &cobra.Command{
+	GroupID: "basic",
+	Use:     "status",
+	Short:   "Show the session state of every context",
+	Long:    "List every context with its cluster, user, remaining session validity, tsh profile and running proxies",
+	Args:    cobra.NoArgs,
+	RunE:    statusRun,
+}
+
+// StatusResult is the output of 'paycast status', one entry per context
+type StatusResult []ContextStatus
+
+// ContextStatus is the state of a single context
+type ContextStatus struct {
+	Name      string    `json:"name"`
+	Current   bool      `json:"current"`
+	Cluster   string    `json:"cluster"`
+	User      string    `json:"user"`
+	Expiry    time.Time `json:"expiry"`
+	Remaining string    `json:"remaining"`
+	Valid     bool      `json:"valid"`
+	Profile   bool      `json:"profile"`
+	Proxies   int       `json:"proxies"`
+}
+
+func (r StatusResult) Header() []string {
+	return []string{"CURRENT", "NAME", "CLUSTER", "USER", "VALID FOR", "PROFILE", "PROXIES"}
+}
+
+func (r StatusResult) Rows() [][]string {
+	rows := make([][]string, 0, len(r))
+
+	for _, status := range r {
+		current := ""
+
+		if status.Current {
+			current = "*"
+		}
+
+		profile := "missing"
+
+		if status.Profile {
+			profile = "present"
+		}
+
+		rows = append(rows, []string{
+			current, status.Name, status.Cluster, status.User, status.Remaining, profile, strconv.Itoa(status.Proxies),
+		})
+	}
+
+	return rows
+}
+
+func NewStatusCommand() *cobra.Command {
+	var watch bool
+	var interval time.Duration
+
+	statusCmd.Flags().BoolVarP(&watch, "watch", "w", false, "Keep refreshing the status until interrupted")
+	statusCmd.Flags().DurationVar(&interval, "interval", 5*time.Second, "Refresh interval for --watch")
+
+	return statusCmd
+}
+
+func statusRun(cobraCmd *cobra.Command, args []string) error {
+	ctx := cobraCmd.Context()
+
+	watch, _ := cobraCmd.Flags().GetBool("watch")
+	interval, _ := cobraCmd.Flags().GetDuration("interval")
+
+	exists, err := store.IsExist(ctx)
+
+	if err != nil {
+		return fmt.Errorf("failed to check configuration file: %w", err)
+	}
+
+	if !exists {
+		return store.ErrConfigNotFound
+	}
+
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+
+	for {
+		config, err := store.Get(ctx)
+
+		if err != nil {
+			return fmt.Errorf("failed to load configuration file: %w", err)
+		}
+
+		statuses := Collect(config, time.Now())
+
+		if watch && (output.Current() == output.Text || output.Current() == output.Table) {
+			// Clear the screen before redrawing
+			fmt.Print("\033[H\033[2J")
+		}
+
+		err = output.Print(statuses)
+
+		if err != nil {
+			return fmt.Errorf("failed to write status: %w", err)
+		}
+
+		if !watch {
+			return nil
+		}
+
+		select {
+		case <-c:
+			return nil
+		case <-time.After(interval):
+		}
+	}
+}
+
+// Collect gathers the status of every context in the configuration, sorted
+// by name. It never runs tsh, so it is cheap to call repeatedly
+func Collect(config store.Config, now time.Time) StatusResult {
+	statuses := make(StatusResult, 0, len(config.Contexts))
+
+	for name, configContext := range config.Contexts {
+		processes, err := store.ListProcesses(name)
+
+		if err != nil {
+			logger.Warn().
+				Err(err).
+				Str("context", name).
+				Msg("Failed to list running proxies")
+		}
+
+		processes = slices.DeleteFunc(processes, func(process store.Process) bool {
+			return process.Kind == store.SUPERVISOR_KIND
+		})
+
+		statuses = append(statuses, ContextStatus{
+			Name:      name,
+			Current:   name == config.Active(),
+			Cluster:   configContext.Cluster,
+			User:      configContext.User,
+			Expiry:    configContext.Expiry,
+			Remaining: Humanize(configContext.Expiry.Sub(now)),
+			Valid:     now.Before(configContext.Expiry),
+			Profile:   cmd.HasProfile(configContext.TeleportHome(), configContext.Proxy),
+			Proxies:   len(processes),
+		})
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Name < statuses[j].Name
+	})
+
+	return statuses
+}
+
+// Humanize formats the remaining validity of a session, e.g. "3h12m" or
+// "expired" once it is not positive anymore
+func Humanize(d time.Duration) string {
+	if d <= 0 {
+		return "expired"
+	}
+
+	d = d.Round(time.Minute)
+
+	days := d / (24 * time.Hour)
+	hours := (d % (24 * time.Hour)) / time.Hour
+	minutes := (d % time.Hour) / time.Minute
+
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd%dh", days, hours)
+	case hours > 0:
+		return fmt.Sprintf("%dh%dm", hours, minutes)
+	case minutes > 0:
+		return fmt.Sprintf("%dm", minutes)
+	}
+
+	return "<1m"
+}
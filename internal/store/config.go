@@ -4,7 +4,12 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
+
+	"github.com/RiskyFeryansyahP/paycast/pkg/exit"
 )
 
 const (
@@ -15,35 +20,335 @@ const (
 
 // Error messages
 var (
-	ErrConfigNotFound = fmt.Errorf("no paycast configuration found\nRun 'paycast config set-context <name> <url> --proxy=<proxy> --auth=<auth> --user=<user>' to get started")
-	ErrNoContext      = fmt.Errorf("no context configured\nRun 'paycast config set-context <name> <url> --proxy=<proxy> --auth=<auth> --user=<user>' to create a context")
+	ErrConfigNotFound = exit.New(exit.ContextMissing, fmt.Errorf("no paycast configuration found\nRun 'paycast config set-context <name> <url> --proxy=<proxy> --auth=<auth> --user=<user>' to get started"), "")
+	ErrNoContext      = exit.New(exit.ContextMissing, fmt.Errorf("no context configured\nRun 'paycast config set-context <name> <url> --proxy=<proxy> --auth=<auth> --user=<user>' to create a context"), "")
 )
 
+const CONTEXTS_DIR = "contexts"
+
 // GetConfigPath returns the full path to the config file
 func GetConfigPath() string {
 	homeDir, _ := os.UserHomeDir()
 	return filepath.Join(homeDir, DIR, FILE_NAME)
 }
 
+// GetContextDir returns the directory holding private files of a context
+func GetContextDir(name string) string {
+	homeDir, _ := os.UserHomeDir()
+	return filepath.Join(homeDir, DIR, CONTEXTS_DIR, name)
+}
+
+// Context is an authenticated teleport session and everything paycast runs
+// through it. CredentialHelper is "env", "file", "file:<path>" or a shell
+// command supplying login secrets, see pkg/cmd.RequestCredential. TTL is the
+// requested session length in minutes. Environment is a free form label
+// such as production or staging, used to color the prompt segment
 type Context struct {
-	Database map[string]Database `json:"dbs"`
-	Name     string              `json:"name"`
-	Cluster  string              `json:"cluster"`
-	Profile  string              `json:"profile"`
-	Proxy    string              `json:"proxy"`
-	Auth     string              `json:"auth"`
-	User     string              `json:"user"`
-	Expiry   time.Time           `json:"expiry"`
+	Database         map[string]Database  `json:"dbs"`
+	Apps             map[string]App       `json:"apps,omitempty"`
+	Hosts            map[string]Host      `json:"hosts,omitempty"`
+	Workspaces       map[string]Workspace `json:"workspaces,omitempty"`
+	Hooks            []Hook               `json:"hooks,omitempty"`
+	Name             string               `json:"name"`
+	Environment      string               `json:"environment,omitempty"`
+	URL              string               `json:"url,omitempty"`
+	LeafClusters     []string             `json:"leaf_clusters,omitempty"`
+	Cluster          string               `json:"cluster"`
+	Profile          string               `json:"profile"`
+	Proxy            string               `json:"proxy"`
+	Auth             string               `json:"auth"`
+	User             string               `json:"user"`
+	Roles            []string             `json:"roles,omitempty"`
+	Traits           map[string][]string  `json:"traits,omitempty"`
+	Logins           []string             `json:"logins,omitempty"`
+	ActiveRequests   []string             `json:"active_requests,omitempty"`
+	Prompts          []PromptRule         `json:"prompts,omitempty"`
+	Browser          string               `json:"browser,omitempty"`
+	CredentialHelper string               `json:"credential_helper,omitempty"`
+	Isolated         bool                 `json:"isolated,omitempty"`
+	TTL              int                  `json:"ttl,omitempty"`
+	RequestIDs       []string             `json:"request_ids,omitempty"`
+	RequestExpiry    map[string]time.Time `json:"request_expiry,omitempty"`
+	PendingRequests  []string             `json:"pending_requests,omitempty"`
+	LoginArgs        []string             `json:"login_args,omitempty"`
+	KubeClusters     []string             `json:"kube_clusters,omitempty"`
+	KubeCluster      string               `json:"kube_cluster,omitempty"`
+	Expiry           time.Time            `json:"expiry"`
+}
+
+// TeleportHome returns the private tsh home of an isolated context, or an
+// empty string when the context shares the global ~/.tsh profile
+func (c Context) TeleportHome() string {
+	if !c.Isolated {
+		return ""
+	}
+
+	return filepath.Join(GetContextDir(c.Name), "tsh")
+}
+
+// CheckSession fails once the session of the context has expired, for
+// commands that need a session but do not log in again themselves
+func (c Context) CheckSession(now time.Time) error {
+	if now.Before(c.Expiry) {
+		return nil
+	}
+
+	return exit.New(exit.SessionExpired,
+		fmt.Errorf("session of context '%s' expired", c.Name),
+		"Run 'paycast login' to renew it")
+}
+
+// LeafCluster checks that cluster is a leaf cluster declared in the context,
+// databases, apps and hosts store "" for the root cluster so its name is
+// normalized to ""
+func (c Context) LeafCluster(cluster string) (string, error) {
+	if cluster == "" || cluster == c.Cluster {
+		return "", nil
+	}
+
+	if !slices.Contains(c.LeafClusters, cluster) {
+		return "", exit.New(exit.Failure,
+			fmt.Errorf("leaf cluster '%s' not declared in context '%s'", cluster, c.Name),
+			"Run 'paycast config add-cluster' to declare it first")
+	}
+
+	return cluster, nil
+}
+
+// ClusterOwner returns "<kind>/<name>" of a database, app or SSH host of the
+// context reached through the leaf cluster
+func (c Context) ClusterOwner(cluster string) (string, bool) {
+	for _, db := range c.Database {
+		if db.Cluster == cluster {
+			return "db/" + db.Tunnel, true
+		}
+	}
+
+	for _, app := range c.Apps {
+		if app.Cluster == cluster {
+			return "app/" + app.Name, true
+		}
+	}
+
+	for _, host := range c.Hosts {
+		if host.Cluster == cluster {
+			return "ssh/" + host.Alias, true
+		}
+	}
+
+	return "", false
+}
+
+// WorkspaceUsing returns the first workspace of the context, by name, that
+// includes member, a "<kind>/<name>" as returned by PortOwner
+func (c Context) WorkspaceUsing(member string) (string, bool) {
+	kind, name, _ := strings.Cut(member, "/")
+
+	names := make([]string, 0, len(c.Workspaces))
+
+	for workspaceName := range c.Workspaces {
+		names = append(names, workspaceName)
+	}
+
+	slices.Sort(names)
+
+	for _, workspaceName := range names {
+		workspace := c.Workspaces[workspaceName]
+
+		var members []string
+
+		switch kind {
+		case "db":
+			members = workspace.Databases
+		case "app":
+			members = workspace.Apps
+		case "ssh":
+			members = workspace.Tunnels
+		}
+
+		if slices.Contains(members, name) {
+			return workspaceName, true
+		}
+	}
+
+	return "", false
+}
+
+// Kubeconfig returns the kubeconfig paycast writes for the context
+func (c Context) Kubeconfig() string {
+	return filepath.Join(GetContextDir(c.Name), "kubeconfig")
+}
+
+// PromptRule overrides how a tsh login prompt is answered. Action is one of
+// secret, input, choose, notify, fail or browser
+type PromptRule struct {
+	Name    string `json:"name"`
+	Pattern string `json:"pattern"`
+	Action  string `json:"action"`
+	Kind    string `json:"kind,omitempty"`
+	Option  string `json:"option,omitempty"`
+	Message string `json:"message,omitempty"`
 }
 
 type Database struct {
-	User   string `json:"user"`
-	Tunnel string `json:"tunnel"`
-	Name   string `json:"name"`
-	Port   int32  `json:"port"`
+	User    string `json:"user"`
+	Tunnel  string `json:"tunnel"`
+	Name    string `json:"name"`
+	Port    int32  `json:"port"`
+	Cluster string `json:"cluster,omitempty"`
+	Hooks   []Hook `json:"hooks,omitempty"`
+}
+
+// Hook is a shell command run when Event happens. Timeout and Before are
+// durations, Before only applies to session_expiring. OnFailure is one of
+// warn, ignore or abort
+type Hook struct {
+	Event     string `json:"event"`
+	Command   string `json:"command"`
+	Timeout   string `json:"timeout,omitempty"`
+	Before    string `json:"before,omitempty"`
+	OnFailure string `json:"on_failure,omitempty"`
+}
+
+type App struct {
+	Name    string `json:"name"`
+	Port    int32  `json:"port"`
+	Cluster string `json:"cluster,omitempty"`
+}
+
+// Host is an SSH bookmark, Forwards are opened as local port forwards
+type Host struct {
+	Alias    string    `json:"alias"`
+	Login    string    `json:"login"`
+	Host     string    `json:"host"`
+	Cluster  string    `json:"cluster,omitempty"`
+	Forwards []Forward `json:"forwards,omitempty"`
+}
+
+// Forward forwards LocalPort to RemoteHost:RemotePort as seen from the host
+type Forward struct {
+	LocalPort  int32  `json:"local_port"`
+	RemoteHost string `json:"remote_host"`
+	RemotePort int32  `json:"remote_port"`
+}
+
+// ParseForward parses a forward in the "local_port:remote_host:remote_port"
+// form used by ssh -L
+func ParseForward(value string) (Forward, error) {
+	parts := strings.Split(value, ":")
+
+	if len(parts) != 3 {
+		return Forward{}, fmt.Errorf("invalid forward '%s', expected local_port:remote_host:remote_port", value)
+	}
+
+	localPort, err := strconv.ParseInt(parts[0], 10, 32)
+
+	if err != nil {
+		return Forward{}, fmt.Errorf("invalid local port in forward '%s': %w", value, err)
+	}
+
+	remotePort, err := strconv.ParseInt(parts[2], 10, 32)
+
+	if err != nil {
+		return Forward{}, fmt.Errorf("invalid remote port in forward '%s': %w", value, err)
+	}
+
+	return Forward{
+		LocalPort:  int32(localPort),
+		RemoteHost: parts[1],
+		RemotePort: int32(remotePort),
+	}, nil
+}
+
+func (f Forward) String() string {
+	return fmt.Sprintf("%d:%s:%d", f.LocalPort, f.RemoteHost, f.RemotePort)
+}
+
+// Workspace is a named bundle of databases, apps and SSH tunnels started
+// together, referenced by their tunnel, name and alias
+type Workspace struct {
+	Name      string   `json:"name"`
+	Databases []string `json:"dbs,omitempty"`
+	Apps      []string `json:"apps,omitempty"`
+	Tunnels   []string `json:"tunnels,omitempty"`
+}
+
+// PortOwner returns "<kind>/<name>" of the database, app or SSH forward of
+// the context configured to listen on port
+func (c Context) PortOwner(port int32) (string, bool) {
+	for _, db := range c.Database {
+		if db.Port == port {
+			return "db/" + db.Tunnel, true
+		}
+	}
+
+	for _, app := range c.Apps {
+		if app.Port == port {
+			return "app/" + app.Name, true
+		}
+	}
+
+	for _, host := range c.Hosts {
+		for _, forward := range host.Forwards {
+			if forward.LocalPort == port {
+				return "ssh/" + host.Alias, true
+			}
+		}
+	}
+
+	return "", false
+}
+
+// LoginRequestIDs returns the approved access requests to assume on login,
+// leaving out those whose access is known to have expired since tsh login
+// fails for them
+func (c Context) LoginRequestIDs(now time.Time) []string {
+	ids := make([]string, 0, len(c.RequestIDs))
+
+	for _, id := range c.RequestIDs {
+		expiry, ok := c.RequestExpiry[id]
+
+		if ok && !now.Before(expiry) {
+			continue
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids
+}
+
+// KeepRequests forgets the access requests that are not among the active
+// requests of the session, so they are not asked for on later logins
+func (c *Context) KeepRequests(active []string) {
+	var ids []string
+
+	for _, id := range c.RequestIDs {
+		if slices.Contains(active, id) {
+			ids = append(ids, id)
+		} else {
+			delete(c.RequestExpiry, id)
+		}
+	}
+
+	c.RequestIDs = ids
 }
 
 type Config struct {
 	Contexts       map[string]Context `json:"contexts"`
 	CurrentContext string             `json:"current_context"`
 }
+
+// CONTEXT_ENV overrides the current context for a single terminal, it is set
+// by 'paycast shell'
+const CONTEXT_ENV = "PAYCAST_CONTEXT"
+
+// Active returns the context commands run against, the one of CONTEXT_ENV
+// when set or else the current context
+func (c Config) Active() string {
+	if name := os.Getenv(CONTEXT_ENV); name != "" {
+		return name
+	}
+
+	return c.CurrentContext
+}
@@ -0,0 +1,196 @@
+package store
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestContextLoginRequestIDs(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	configContext := Context{
+		RequestIDs: []string{"expired", "live", "unknown"},
+		RequestExpiry: map[string]time.Time{
+			"expired": now.Add(-time.Minute),
+			"live":    now.Add(time.Hour),
+		},
+	}
+
+	got := configContext.LoginRequestIDs(now)
+	want := []string{"live", "unknown"}
+
+	if !slices.Equal(got, want) {
+		t.Errorf("LoginRequestIDs() = %v, want %v", got, want)
+	}
+}
+
+func TestContextKeepRequests(t *testing.T) {
+	expiry := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	configContext := Context{
+		RequestIDs: []string{"gone", "active"},
+		RequestExpiry: map[string]time.Time{
+			"gone":   expiry,
+			"active": expiry,
+		},
+	}
+
+	configContext.KeepRequests([]string{"active", "other"})
+
+	if !slices.Equal(configContext.RequestIDs, []string{"active"}) {
+		t.Errorf("RequestIDs = %v, want [active]", configContext.RequestIDs)
+	}
+
+	if _, ok := configContext.RequestExpiry["gone"]; ok || len(configContext.RequestExpiry) != 1 {
+		t.Errorf("RequestExpiry = %v, want only active", configContext.RequestExpiry)
+	}
+}
+
+func TestContextLeafCluster(t *testing.T) {
+	configContext := Context{
+		Name:         "staging",
+		Cluster:      "root.example.com",
+		LeafClusters: []string{"leaf.example.com"},
+	}
+
+	tests := []struct {
+		name    string
+		cluster string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name:    "root cluster",
+			cluster: "root.example.com",
+		},
+		{
+			name:    "declared leaf",
+			cluster: "leaf.example.com",
+			want:    "leaf.example.com",
+		},
+		{
+			name:    "undeclared leaf",
+			cluster: "other.example.com",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := configContext.LeafCluster(tt.cluster)
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LeafCluster() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("LeafCluster() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestContextClusterOwner(t *testing.T) {
+	configContext := Context{
+		Database: map[string]Database{"orders": {Tunnel: "orders", Cluster: "db.example.com"}},
+		Apps:     map[string]App{"grafana": {Name: "grafana", Cluster: "app.example.com"}},
+		Hosts:    map[string]Host{"bastion": {Alias: "bastion", Cluster: "ssh.example.com"}},
+	}
+
+	tests := []struct {
+		name    string
+		cluster string
+		want    string
+		wantOk  bool
+	}{
+		{
+			name:    "database",
+			cluster: "db.example.com",
+			want:    "db/orders",
+			wantOk:  true,
+		},
+		{
+			name:    "app",
+			cluster: "app.example.com",
+			want:    "app/grafana",
+			wantOk:  true,
+		},
+		{
+			name:    "ssh host",
+			cluster: "ssh.example.com",
+			want:    "ssh/bastion",
+			wantOk:  true,
+		},
+		{
+			name:    "unused",
+			cluster: "other.example.com",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := configContext.ClusterOwner(tt.cluster)
+
+			if got != tt.want || ok != tt.wantOk {
+				t.Errorf("ClusterOwner() = %q, %v, want %q, %v", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestContextWorkspaceUsing(t *testing.T) {
+	configContext := Context{
+		Workspaces: map[string]Workspace{
+			"payments": {Name: "payments", Databases: []string{"orders"}, Apps: []string{"grafana"}},
+			"billing":  {Name: "billing", Databases: []string{"orders"}, Tunnels: []string{"bastion"}},
+		},
+	}
+
+	tests := []struct {
+		name   string
+		member string
+		want   string
+		wantOk bool
+	}{
+		{
+			name:   "database of several workspaces",
+			member: "db/orders",
+			want:   "billing",
+			wantOk: true,
+		},
+		{
+			name:   "app",
+			member: "app/grafana",
+			want:   "payments",
+			wantOk: true,
+		},
+		{
+			name:   "ssh host",
+			member: "ssh/bastion",
+			want:   "billing",
+			wantOk: true,
+		},
+		{
+			name:   "same name of another kind",
+			member: "app/orders",
+		},
+		{
+			name:   "unused",
+			member: "db/users",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := configContext.WorkspaceUsing(tt.member)
+
+			if got != tt.want || ok != tt.wantOk {
+				t.Errorf("WorkspaceUsing() = %q, %v, want %q, %v", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/RiskyFeryansyahP/paycast/pkg/logger"
+)
+
+// preview shows the changes saving config would make to the configuration
+// file, as lines removed with '-' and added with '+'
+func preview(w io.Writer, configFile string, config Config) error {
+	configData, err := json.MarshalIndent(config, "", "\t")
+
+	if err != nil {
+		return err
+	}
+
+	current, err := os.ReadFile(configFile)
+
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	changes := diffLines(splitLines(string(current)), splitLines(string(configData)))
+
+	if len(changes) == 0 {
+		logger.Info().
+			Str("path", configFile).
+			Msg("Dry run, configuration would not change")
+
+		return nil
+	}
+
+	logger.Info().
+		Str("path", configFile).
+		Msg("Dry run, configuration not saved, it would change as follows")
+
+	for _, change := range changes {
+		fmt.Fprintln(w, change)
+	}
+
+	return nil
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+
+	return strings.Split(strings.TrimRight(s, "\n"), "\n")
+}
+
+// diffLines returns the changed lines between a and b from their longest
+// common subsequence, the configuration is small enough for the quadratic
+// table
+func diffLines(a, b []string) []string {
+	lcs := make([][]int, len(a)+1)
+
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var changes []string
+
+	i, j := 0, 0
+
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			changes = append(changes, "- "+a[i])
+			i++
+		default:
+			changes = append(changes, "+ "+b[j])
+			j++
+		}
+	}
+
+	return changes
+}
@@ -0,0 +1,75 @@
+package store
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		name string
+		a    []string
+		b    []string
+		want []string
+	}{
+		{
+			name: "equal",
+			a:    []string{"{", "}"},
+			b:    []string{"{", "}"},
+			want: nil,
+		},
+		{
+			name: "new file",
+			a:    nil,
+			b:    []string{"{", "}"},
+			want: []string{"+ {", "+ }"},
+		},
+		{
+			name: "changed line",
+			a:    []string{"{", `"user": "alice"`, "}"},
+			b:    []string{"{", `"user": "bob"`, "}"},
+			want: []string{`- "user": "alice"`, `+ "user": "bob"`},
+		},
+		{
+			name: "added and removed lines",
+			a:    []string{"{", `"a": 1,`, `"b": 2`, "}"},
+			b:    []string{"{", `"b": 2,`, `"c": 3`, "}"},
+			want: []string{`- "a": 1,`, `- "b": 2`, `+ "b": 2,`, `+ "c": 3`},
+		},
+		{
+			name: "removed lines",
+			a:    []string{"{", `"a": 1`, "}"},
+			b:    []string{"{", "}"},
+			want: []string{`- "a": 1`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := diffLines(tt.a, tt.b)
+
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("diffLines() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitLines(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{in: "", want: nil},
+		{in: "a\nb\n", want: []string{"a", "b"}},
+		{in: "a\nb", want: []string{"a", "b"}},
+	}
+
+	for _, tt := range tests {
+		got := splitLines(tt.in)
+
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("splitLines(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/RiskyFeryansyahP/paycast/pkg/dryrun"
+	"github.com/RiskyFeryansyahP/paycast/pkg/logger"
+)
+
+const RUN_DIR = "run"
+
+// SUPERVISOR_KIND is the kind of the paycast process keeping proxies running,
+// recorded so logout can stop it before it logs in again
+const SUPERVISOR_KIND = "supervisor"
+
+// Process is a proxy started by paycast, recorded so other paycast
+// invocations can find and stop it
+type Process struct {
+	PID       int       `json:"pid"`
+	Context   string    `json:"context"`
+	Kind      string    `json:"kind"`
+	Name      string    `json:"name"`
+	Port      int32     `json:"port,omitempty"`
+	Workspace string    `json:"workspace,omitempty"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+func (p Process) path() string {
+	return filepath.Join(GetContextDir(p.Context), RUN_DIR, fmt.Sprintf("%s-%s.json", p.Kind, p.Name))
+}
+
+// Alive reports whether the process is still running
+func (p Process) Alive() bool {
+	err := syscall.Kill(p.PID, 0)
+
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// Stop asks the process to terminate
+func (p Process) Stop() error {
+	if dryrun.Enabled() {
+		logger.Info().
+			Int("pid", p.PID).
+			Str(p.Kind, p.Name).
+			Msg("Dry run, not stopping process")
+
+		return nil
+	}
+
+	return syscall.Kill(p.PID, syscall.SIGTERM)
+}
+
+func RegisterProcess(process Process) error {
+	if dryrun.Enabled() {
+		return nil
+	}
+
+	path := process.path()
+
+	err := os.MkdirAll(filepath.Dir(path), 0700)
+
+	if err != nil {
+		logger.Error().
+			Err(err).
+			Str("path", filepath.Dir(path)).
+			Msg("Failed to create process directory")
+
+		return err
+	}
+
+	data, err := json.MarshalIndent(process, "", "\t")
+
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0600)
+}
+
+func UnregisterProcess(process Process) error {
+	if dryrun.Enabled() {
+		return nil
+	}
+
+	err := os.Remove(process.path())
+
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+// ListProcesses returns the running processes of a context. Records of
+// processes that are gone are removed on the way
+func ListProcesses(contextName string) ([]Process, error) {
+	dir := filepath.Join(GetContextDir(contextName), RUN_DIR)
+
+	entries, err := os.ReadDir(dir)
+
+	if err != nil && os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var processes []Process
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), EXTENSION) {
+			continue
+		}
+
+		path := filepath.Join(dir, entry.Name())
+
+		data, err := os.ReadFile(path)
+
+		if err != nil {
+			return nil, err
+		}
+
+		var process Process
+
+		err = json.Unmarshal(data, &process)
+
+		if err != nil || !process.Alive() {
+			if !dryrun.Enabled() {
+				_ = os.Remove(path)
+			}
+
+			continue
+		}
+
+		processes = append(processes, process)
+	}
+
+	return processes, nil
+}
+
+// StopProcesses terminates the proxies and supervisors paycast started for a
+// context
+func StopProcesses(contextName string) {
+	processes, err := ListProcesses(contextName)
+
+	if err != nil {
+		logger.Warn().
+			Err(err).
+			Str("context", contextName).
+			Msg("Failed to list running proxies")
+
+		return
+	}
+
+	for _, process := range processes {
+		err = process.Stop()
+
+		if err != nil {
+			logger.Warn().
+				Err(err).
+				Int("pid", process.PID).
+				Str("name", process.Name).
+				Msg("Failed to stop proxy")
+
+			continue
+		}
+
+		_ = UnregisterProcess(process)
+
+		logger.Info().
+			Str("context", contextName).
+			Str(process.Kind, process.Name).
+			Msg("Proxy stopped")
+	}
+}
+
+// RemoveContextDir deletes the private files of a context, its tsh home with
+// keys and certificates, its kubeconfig and its process records
+func RemoveContextDir(name string) error {
+	dir := GetContextDir(name)
+
+	if dryrun.Enabled() {
+		logger.Info().
+			Str("path", dir).
+			Msg("Dry run, not removing context directory")
+
+		return nil
+	}
+
+	return os.RemoveAll(dir)
+}
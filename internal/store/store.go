@@ -3,9 +3,12 @@ package store
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
 
+	"github.com/RiskyFeryansyahP/paycast/pkg/dryrun"
+	"github.com/RiskyFeryansyahP/paycast/pkg/exit"
 	"github.com/RiskyFeryansyahP/paycast/pkg/logger"
 )
 
@@ -23,6 +26,10 @@ func New(ctx context.Context, config Config) error {
 	configDir := filepath.Join(homeDir, DIR)
 	configFile := filepath.Join(configDir, FILE_NAME+EXTENSION)
 
+	if dryrun.Enabled() {
+		return preview(os.Stderr, configFile, config)
+	}
+
 	err = os.MkdirAll(configDir, 0700)
 
 	if err != nil {
@@ -65,6 +72,10 @@ func Save(ctx context.Context, config Config) error {
 		return err
 	}
 
+	if dryrun.Enabled() {
+		return preview(os.Stderr, configFile, config)
+	}
+
 	configData, err := json.MarshalIndent(config, "", "\t")
 
 	if err != nil {
@@ -113,6 +124,54 @@ func Get(ctx context.Context) (Config, error) {
 	return config, nil
 }
 
+// Load loads the configuration, failing with a ContextMissing error when
+// paycast has not been set up yet
+func Load(ctx context.Context) (Config, error) {
+	exists, err := IsExist(ctx)
+
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to check configuration file: %w", err)
+	}
+
+	if !exists {
+		return Config{}, ErrConfigNotFound
+	}
+
+	config, err := Get(ctx)
+
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to load configuration file: %w", err)
+	}
+
+	return config, nil
+}
+
+// LoadCurrent loads the configuration and the name of the context commands
+// run against, failing with a ContextMissing error when there is none
+func LoadCurrent(ctx context.Context) (Config, string, error) {
+	config, err := Load(ctx)
+
+	if err != nil {
+		return Config{}, "", err
+	}
+
+	name := config.Active()
+
+	if name == "" {
+		return Config{}, "", ErrNoContext
+	}
+
+	_, ok := config.Contexts[name]
+
+	if !ok {
+		return Config{}, "", exit.New(exit.ContextMissing,
+			fmt.Errorf("context '%s' not found", name),
+			"Run 'paycast status' to see available contexts")
+	}
+
+	return config, name, nil
+}
+
 func IsExist(ctx context.Context) (bool, error) {
 	configFile, err := getConfigFile()
 
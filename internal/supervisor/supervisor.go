@@ -0,0 +1,468 @@
+package supervisor
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/RiskyFeryansyahP/paycast/internal/hook"
+	"github.com/RiskyFeryansyahP/paycast/internal/store"
+	"github.com/RiskyFeryansyahP/paycast/pkg/cmd"
+	"github.com/RiskyFeryansyahP/paycast/pkg/dryrun"
+	"github.com/RiskyFeryansyahP/paycast/pkg/exit"
+	"github.com/RiskyFeryansyahP/paycast/pkg/logger"
+	"github.com/creack/pty"
+)
+
+const (
+	// stopTimeout is how long a proxy gets to exit after SIGTERM before it is killed
+	stopTimeout = 5 * time.Second
+
+	// readyTimeout is how long a proxy may take to accept connections
+	readyTimeout = 30 * time.Second
+)
+
+// Proxy is a long running tsh process listening on local ports. Proxies of
+// a lower Stage are started and ready before the next stage starts, Workspace
+// names the workspace the proxy was started for. Hooks run next to the hooks
+// of the context for the proxy_ready and proxy_exit events of this proxy
+type Proxy struct {
+	Kind      string
+	Name      string
+	Ports     []int32
+	Args      []string
+	Fields    map[string]string
+	Stage     int
+	Workspace string
+	Hooks     []store.Hook
+}
+
+// port returns the main local port of the proxy
+func (p Proxy) port() int32 {
+	if len(p.Ports) == 0 {
+		return 0
+	}
+
+	return p.Ports[0]
+}
+
+// runHooks runs the context and proxy hooks attached to a proxy event
+func (p Proxy) runHooks(ctx context.Context, configContext store.Context, name string, cause error) error {
+	event := hook.NewEvent(name, configContext)
+	event.Kind = p.Kind
+	event.Target = p.Name
+	event.Port = p.port()
+	event.Workspace = p.Workspace
+
+	if cause != nil {
+		event.Error = cause.Error()
+	}
+
+	return hook.Run(ctx, slices.Concat(configContext.Hooks, p.Hooks), event)
+}
+
+// CheckPorts fails when a local port of the proxies is already taken
+func CheckPorts(proxies []Proxy) error {
+	for _, proxy := range proxies {
+		for _, port := range proxy.Ports {
+			listener, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+
+			if err != nil {
+				return exit.New(exit.PortInUse,
+					fmt.Errorf("port %d of %s '%s' is already in use: %w", port, proxy.Kind, proxy.Name, err),
+					"Stop the process listening on the port or choose a different port")
+			}
+
+			_ = listener.Close()
+		}
+	}
+
+	return nil
+}
+
+// Run starts the proxies of a context and keeps them running until ctx is
+// cancelled or an interrupt arrives. When the session expires the context is
+// logged in again, saved, and the proxies are restarted with the new
+// credentials. session_expiring hooks run ahead of the expiry
+func Run(ctx context.Context, config store.Config, contextName string, proxies []Proxy) error {
+	return run(ctx, config, contextName, proxies, nil)
+}
+
+// Start runs the proxies like Run in the background and returns once they
+// were started for the first time. The returned channel receives the result
+// of Run after ctx is cancelled
+func Start(ctx context.Context, config store.Config, contextName string, proxies []Proxy) (<-chan error, error) {
+	started := make(chan struct{})
+	done := make(chan error, 1)
+
+	go func() {
+		done <- run(ctx, config, contextName, proxies, sync.OnceFunc(func() {
+			close(started)
+		}))
+	}()
+
+	select {
+	case <-started:
+		return done, nil
+	case err := <-done:
+		if err == nil {
+			err = ctx.Err()
+		}
+
+		return nil, err
+	}
+}
+
+// run is Run calling started, when not nil, after the proxies were started
+func run(ctx context.Context, config store.Config, contextName string, proxies []Proxy, started func()) error {
+	configContext := config.Contexts[contextName]
+
+	err := CheckPorts(proxies)
+
+	if err != nil {
+		return err
+	}
+
+	// Record the supervisor itself, stopping only its proxies would have
+	// it log in again once the session expires
+	process := store.Process{
+		PID:       os.Getpid(),
+		Context:   contextName,
+		Kind:      store.SUPERVISOR_KIND,
+		Name:      strconv.Itoa(os.Getpid()),
+		StartedAt: time.Now(),
+	}
+
+	err = store.RegisterProcess(process)
+
+	if err != nil {
+		logger.Warn().
+			Err(err).
+			Msg("Failed to record supervisor process, 'paycast logout' will not stop it")
+	}
+	defer store.UnregisterProcess(process)
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	for {
+		configContext, err = EnsureSession(ctx, config, contextName)
+
+		if err != nil {
+			return err
+		}
+
+		// Show the proxy command lines instead of running them
+		if dryrun.Enabled() {
+			for _, proxy := range proxies {
+				cmd.Command(ctx, configContext.TeleportHome(), proxy.Args...)
+			}
+
+			if started != nil {
+				started()
+			}
+
+			return nil
+		}
+
+		runCtx, cancel := context.WithCancel(ctx)
+		var wg sync.WaitGroup
+
+		ready, err := startStages(runCtx, &wg, configContext, proxies)
+
+		if err != nil {
+			cancel()
+			wg.Wait()
+
+			return err
+		}
+
+		if ready {
+			logger.Info().
+				Str("context", contextName).
+				Int("proxies", len(proxies)).
+				Msg("All proxies ready")
+		}
+
+		logger.Info().
+			Str("context", contextName).
+			Time("expiry", configContext.Expiry.Local()).
+			Msg("Session valid, will relogin when it expires")
+
+		if started != nil {
+			started()
+		}
+
+		if !waitExpiry(ctx, configContext) {
+			logger.Info().Msg("Shutting down proxies...")
+
+			cancel()
+			wg.Wait()
+
+			return nil
+		}
+
+		logger.Info().
+			Str("context", contextName).
+			Msg("Session expired, restarting proxies after relogin")
+
+		cancel()
+		wg.Wait()
+	}
+}
+
+// waitExpiry blocks until the session of the context expires, running the
+// session_expiring hooks on the way. It returns false when ctx is cancelled
+// first
+func waitExpiry(ctx context.Context, configContext store.Context) bool {
+	timer := time.NewTimer(time.Until(configContext.Expiry))
+	defer timer.Stop()
+
+	// A nil channel never fires, so contexts without session_expiring hooks
+	// only wait for the expiry itself
+	var expiring <-chan time.Time
+
+	before := hook.ExpiringBefore(configContext.Hooks)
+
+	if before > 0 {
+		expiringTimer := time.NewTimer(time.Until(configContext.Expiry.Add(-before)))
+		defer expiringTimer.Stop()
+
+		expiring = expiringTimer.C
+	}
+
+	for {
+		select {
+		case <-expiring:
+			expiring = nil
+
+			_ = hook.Run(ctx, configContext.Hooks, hook.NewEvent(hook.SessionExpiring, configContext))
+		case <-ctx.Done():
+			return false
+		case <-timer.C:
+			return true
+		}
+	}
+}
+
+// startStages starts the proxies stage by stage, waiting for the ports of a
+// stage to accept connections before starting the next one. It reports
+// whether every proxy became ready, and fails when a proxy_ready hook aborts
+func startStages(ctx context.Context, wg *sync.WaitGroup, configContext store.Context, proxies []Proxy) (bool, error) {
+	stages := make(map[int][]Proxy)
+
+	for _, proxy := range proxies {
+		stages[proxy.Stage] = append(stages[proxy.Stage], proxy)
+	}
+
+	order := make([]int, 0, len(stages))
+
+	for stage := range stages {
+		order = append(order, stage)
+	}
+
+	slices.Sort(order)
+
+	ready := true
+
+	for _, stage := range order {
+		for _, proxy := range stages[stage] {
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+
+				runProxy(ctx, configContext, proxy)
+			}()
+		}
+
+		for _, proxy := range stages[stage] {
+			err := waitReady(ctx, proxy)
+
+			if err != nil {
+				ready = false
+
+				logger.Error().
+					Err(err).
+					Str(proxy.Kind, proxy.Name).
+					Msg("Proxy did not become ready")
+
+				continue
+			}
+
+			err = proxy.runHooks(ctx, configContext, hook.ProxyReady, nil)
+
+			if err != nil {
+				return false, err
+			}
+		}
+	}
+
+	return ready, nil
+}
+
+// waitReady waits until every port of the proxy accepts connections
+func waitReady(ctx context.Context, proxy Proxy) error {
+	deadline := time.Now().Add(readyTimeout)
+
+	for _, port := range proxy.Ports {
+		address := fmt.Sprintf("127.0.0.1:%d", port)
+
+		for {
+			conn, err := net.DialTimeout("tcp", address, time.Second)
+
+			if err == nil {
+				_ = conn.Close()
+				break
+			}
+
+			if time.Now().After(deadline) {
+				return fmt.Errorf("port %d not accepting connections after %s: %w", port, readyTimeout, err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(200 * time.Millisecond):
+			}
+		}
+	}
+
+	logger.Debug().
+		Str(proxy.Kind, proxy.Name).
+		Msg("Proxy ready")
+
+	return nil
+}
+
+// EnsureSession logs the context in again when its session has expired and
+// saves the renewed context into config. The configuration is read again
+// before saving since other paycast processes may have changed it after
+// config was loaded, only the renewed context is replaced
+func EnsureSession(ctx context.Context, config store.Config, contextName string) (store.Context, error) {
+	configContext := config.Contexts[contextName]
+
+	if time.Now().Before(configContext.Expiry) {
+		return configContext, nil
+	}
+
+	updatedConfigContext, err := cmd.Relogin(ctx, &configContext)
+
+	if err != nil {
+		return configContext, err
+	}
+
+	config.Contexts[contextName] = *updatedConfigContext
+
+	latest, err := store.Get(ctx)
+
+	if err != nil {
+		return *updatedConfigContext, err
+	}
+
+	if latest.Contexts == nil {
+		latest.Contexts = make(map[string]store.Context)
+	}
+
+	latest.Contexts[contextName] = *updatedConfigContext
+
+	err = store.Save(ctx, latest)
+
+	if err != nil {
+		return *updatedConfigContext, err
+	}
+
+	return *updatedConfigContext, nil
+}
+
+// runProxy runs a single proxy until it exits or ctx is cancelled
+func runProxy(ctx context.Context, configContext store.Context, proxy Proxy) {
+	proxyCmd := cmd.Command(ctx, configContext.TeleportHome(), proxy.Args...)
+	proxyCmd.Cancel = func() error {
+		return proxyCmd.Process.Signal(syscall.SIGTERM)
+	}
+	proxyCmd.WaitDelay = stopTimeout
+
+	ptyF, err := pty.Start(proxyCmd)
+
+	if err != nil {
+		logger.Error().
+			Err(err).
+			Str(proxy.Kind, proxy.Name).
+			Msg("Failed to start proxy")
+
+		return
+	}
+	defer ptyF.Close()
+
+	// Drain the output so tsh never blocks on a full terminal buffer
+	go func() {
+		scanner := bufio.NewScanner(ptyF)
+
+		for scanner.Scan() {
+			logger.Debug().
+				Str(proxy.Kind, proxy.Name).
+				Msg(cmd.CleanOutput(scanner.Text()))
+		}
+	}()
+
+	process := store.Process{
+		PID:       proxyCmd.Process.Pid,
+		Context:   configContext.Name,
+		Kind:      proxy.Kind,
+		Name:      proxy.Name,
+		Port:      proxy.port(),
+		Workspace: proxy.Workspace,
+		StartedAt: time.Now(),
+	}
+
+	err = store.RegisterProcess(process)
+
+	if err != nil {
+		logger.Warn().
+			Err(err).
+			Str(proxy.Kind, proxy.Name).
+			Msg("Failed to record proxy process")
+	}
+	defer store.UnregisterProcess(process)
+
+	event := logger.Info().
+		Str(proxy.Kind, proxy.Name).
+		Int("port", int(proxy.port())).
+		Str("host", "localhost")
+
+	if len(proxy.Ports) > 1 {
+		event = event.Ints32("ports", proxy.Ports)
+	}
+
+	for key, value := range proxy.Fields {
+		event = event.Str(key, value)
+	}
+
+	event.Msg("Proxy started")
+
+	err = proxyCmd.Wait()
+
+	if ctx.Err() != nil {
+		err = nil
+	}
+
+	if err != nil {
+		logger.Error().
+			Err(err).
+			Str(proxy.Kind, proxy.Name).
+			Msg("Proxy terminated with error")
+	}
+
+	// The proxy may have been stopped by cancelling ctx, exit hooks still
+	// get to run
+	_ = proxy.runHooks(context.WithoutCancel(ctx), configContext, hook.ProxyExit, err)
+}
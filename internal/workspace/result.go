@@ -0,0 +1,38 @@
+package workspace
+
+import (
+	"strconv"
+	"strings"
+)
+
+// WorkspacesResult is the output of 'workspace list'
+type WorkspacesResult []WorkspaceEntry
+
+// WorkspaceEntry is a workspace of the context and whether it is up
+type WorkspaceEntry struct {
+	Name      string   `json:"name"`
+	Databases []string `json:"dbs,omitempty"`
+	Apps      []string `json:"apps,omitempty"`
+	Tunnels   []string `json:"tunnels,omitempty"`
+	Up        bool     `json:"up"`
+}
+
+func (r WorkspacesResult) Header() []string {
+	return []string{"NAME", "DBS", "APPS", "TUNNELS", "UP"}
+}
+
+func (r WorkspacesResult) Rows() [][]string {
+	rows := make([][]string, 0, len(r))
+
+	for _, workspace := range r {
+		rows = append(rows, []string{
+			workspace.Name,
+			strings.Join(workspace.Databases, ","),
+			strings.Join(workspace.Apps, ","),
+			strings.Join(workspace.Tunnels, ","),
+			strconv.FormatBool(workspace.Up),
+		})
+	}
+
+	return rows
+}
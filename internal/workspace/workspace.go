@@ -0,0 +1,439 @@
+package workspace
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/RiskyFeryansyahP/paycast/internal/app"
+	"github.com/RiskyFeryansyahP/paycast/internal/completion"
+	"github.com/RiskyFeryansyahP/paycast/internal/database"
+	"github.com/RiskyFeryansyahP/paycast/internal/ssh"
+	"github.com/RiskyFeryansyahP/paycast/internal/store"
+	"github.com/RiskyFeryansyahP/paycast/internal/supervisor"
+	"github.com/RiskyFeryansyahP/paycast/pkg/dryrun"
+	"github.com/RiskyFeryansyahP/paycast/pkg/exit"
+	"github.com/RiskyFeryansyahP/paycast/pkg/logger"
+	"github.com/RiskyFeryansyahP/paycast/pkg/output"
+	"github.com/spf13/cobra"
+)
+
+// Stages in which the members of a workspace are started, tunnels first since
+// databases and apps may be reached through them
+const (
+	stageTunnels = iota
+	stageDatabases
+	stageApps
+)
+
+var workspaceCmd = &cobra.Command{
+	GroupID: "basic",
+	Use:     "workspace",
+	Short:   "Manage workspaces",
+	Long:    "Group databases, apps and SSH tunnels of the current context into workspaces started together",
+}
+
+var workspaceCreateCmd = &cobra.Command{
+	Use:   "create <name>",
+	Short: "Create or update a workspace",
+	Long:  "Create a workspace from configured databases, apps and SSH tunnels of the current context",
+	Args:  cobra.ExactArgs(1),
+	RunE:  workspaceCreateRun,
+}
+
+var workspaceListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List workspaces",
+	Long:  "List the workspaces of the current context",
+	Args:  cobra.NoArgs,
+	RunE:  workspaceListRun,
+}
+
+var workspaceDeleteCmd = &cobra.Command{
+	Use:               "delete <name>",
+	Short:             "Delete a workspace",
+	Long:              "Delete the specified workspace from the current context, its members are kept",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completion.First(completion.Workspaces),
+	RunE:              workspaceDeleteRun,
+}
+
+var upCmd = &cobra.Command{
+	GroupID:           "basic",
+	Use:               "up <workspace>",
+	Short:             "Start every proxy of a workspace",
+	Long:              "Start the tunnels, databases and apps of a workspace in order and keep them running until interrupted",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completion.First(completion.Workspaces),
+	RunE:              upRun,
+}
+
+var downCmd = &cobra.Command{
+	GroupID:           "basic",
+	Use:               "down <workspace>",
+	Short:             "Stop every proxy of a workspace",
+	Long:              "Stop a workspace started with 'paycast up' from another terminal",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completion.First(completion.Workspaces),
+	RunE:              downRun,
+}
+
+func NewWorkspaceCommand() *cobra.Command {
+	var dbs, apps, tunnels []string
+
+	workspaceCreateCmd.Flags().StringArrayVar(&dbs, "db", nil, "Database tunnel to include, repeatable")
+	workspaceCreateCmd.Flags().StringArrayVar(&apps, "app", nil, "Application to include, repeatable")
+	workspaceCreateCmd.Flags().StringArrayVar(&tunnels, "tunnel", nil, "SSH host alias whose forwards to include, repeatable")
+	_ = workspaceCreateCmd.RegisterFlagCompletionFunc("db", completion.Databases)
+	_ = workspaceCreateCmd.RegisterFlagCompletionFunc("app", completion.Apps)
+	_ = workspaceCreateCmd.RegisterFlagCompletionFunc("tunnel", completion.Hosts)
+
+	workspaceCmd.AddCommand(workspaceCreateCmd, workspaceDeleteCmd, workspaceListCmd)
+
+	return workspaceCmd
+}
+
+func NewUpCommand() *cobra.Command {
+	return upCmd
+}
+
+func NewDownCommand() *cobra.Command {
+	return downCmd
+}
+
+func workspaceCreateRun(cobraCmd *cobra.Command, args []string) error {
+	ctx := cobraCmd.Context()
+
+	dbs, _ := cobraCmd.Flags().GetStringArray("db")
+	apps, _ := cobraCmd.Flags().GetStringArray("app")
+	tunnels, _ := cobraCmd.Flags().GetStringArray("tunnel")
+
+	config, currentContext, err := store.LoadCurrent(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	configContext := config.Contexts[currentContext]
+
+	name := args[0]
+
+	if len(dbs)+len(apps)+len(tunnels) == 0 {
+		return exit.New(exit.Failure,
+			fmt.Errorf("workspace '%s' has no members", name),
+			"Add members with --db, --app or --tunnel")
+	}
+
+	workspace := store.Workspace{
+		Name:      name,
+		Databases: dbs,
+		Apps:      apps,
+		Tunnels:   tunnels,
+	}
+
+	_, err = Proxies(configContext, workspace)
+
+	if err != nil {
+		return err
+	}
+
+	if len(configContext.Workspaces) == 0 {
+		configContext.Workspaces = make(map[string]store.Workspace)
+	}
+
+	configContext.Workspaces[name] = workspace
+	config.Contexts[currentContext] = configContext
+
+	err = store.Save(ctx, config)
+
+	if err != nil {
+		return fmt.Errorf("failed to save configuration file: %w", err)
+	}
+
+	logger.Info().
+		Str("workspace", name).
+		Strs("dbs", dbs).
+		Strs("apps", apps).
+		Strs("tunnels", tunnels).
+		Msg(dryrun.Message("Workspace saved successfully", "Dry run, workspace would be saved"))
+
+	return nil
+}
+
+func workspaceDeleteRun(cobraCmd *cobra.Command, args []string) error {
+	ctx := cobraCmd.Context()
+
+	config, currentContext, err := store.LoadCurrent(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	configContext := config.Contexts[currentContext]
+
+	name := args[0]
+
+	_, err = findWorkspace(configContext, name)
+
+	if err != nil {
+		return err
+	}
+
+	delete(configContext.Workspaces, name)
+	config.Contexts[currentContext] = configContext
+
+	err = store.Save(ctx, config)
+
+	if err != nil {
+		return fmt.Errorf("failed to save configuration file: %w", err)
+	}
+
+	logger.Info().
+		Str("workspace", name).
+		Msg(dryrun.Message("Workspace deleted successfully", "Dry run, workspace would be deleted"))
+
+	return nil
+}
+
+func workspaceListRun(cobraCmd *cobra.Command, args []string) error {
+	config, currentContext, err := store.LoadCurrent(cobraCmd.Context())
+
+	if err != nil {
+		return err
+	}
+
+	configContext := config.Contexts[currentContext]
+
+	names := make([]string, 0, len(configContext.Workspaces))
+
+	for name := range configContext.Workspaces {
+		names = append(names, name)
+	}
+
+	slices.Sort(names)
+
+	processes, _ := store.ListProcesses(currentContext)
+
+	result := make(WorkspacesResult, 0, len(names))
+
+	for _, name := range names {
+		workspace := configContext.Workspaces[name]
+
+		up := slices.ContainsFunc(processes, func(process store.Process) bool {
+			return process.Kind == "workspace" && process.Name == name
+		})
+
+		result = append(result, WorkspaceEntry{
+			Name:      name,
+			Databases: workspace.Databases,
+			Apps:      workspace.Apps,
+			Tunnels:   workspace.Tunnels,
+			Up:        up,
+		})
+	}
+
+	err = output.Print(result)
+
+	if err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+
+	return nil
+}
+
+func upRun(cobraCmd *cobra.Command, args []string) error {
+	ctx := cobraCmd.Context()
+
+	config, currentContext, err := store.LoadCurrent(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	configContext := config.Contexts[currentContext]
+
+	name := args[0]
+	workspace, err := findWorkspace(configContext, name)
+
+	if err != nil {
+		return err
+	}
+
+	proxies, err := Proxies(configContext, workspace)
+
+	if err != nil {
+		return err
+	}
+
+	processes, err := store.ListProcesses(currentContext)
+
+	if err != nil {
+		return fmt.Errorf("failed to list running proxies: %w", err)
+	}
+
+	for _, process := range processes {
+		if process.Kind == "workspace" && process.Name == name {
+			return exit.New(exit.Failure,
+				fmt.Errorf("workspace '%s' is already up with pid %d", name, process.PID),
+				"Run 'paycast down' to stop it first")
+		}
+	}
+
+	// Record the supervisor itself so 'paycast down' can stop the workspace
+	process := store.Process{
+		PID:       os.Getpid(),
+		Context:   currentContext,
+		Kind:      "workspace",
+		Name:      name,
+		Workspace: name,
+		StartedAt: time.Now(),
+	}
+
+	err = store.RegisterProcess(process)
+
+	if err != nil {
+		logger.Warn().
+			Err(err).
+			Msg("Failed to record workspace process, 'paycast down' will not find it")
+	}
+
+	logger.Info().
+		Str("workspace", name).
+		Int("proxies", len(proxies)).
+		Msg("Starting workspace")
+
+	err = supervisor.Run(ctx, config, currentContext, proxies)
+
+	_ = store.UnregisterProcess(process)
+
+	if err != nil {
+		return fmt.Errorf("failed to run workspace '%s': %w", name, err)
+	}
+
+	return nil
+}
+
+func downRun(cobraCmd *cobra.Command, args []string) error {
+	config, currentContext, err := store.LoadCurrent(cobraCmd.Context())
+
+	if err != nil {
+		return err
+	}
+
+	name := args[0]
+
+	_, err = findWorkspace(config.Contexts[currentContext], name)
+
+	if err != nil {
+		return err
+	}
+
+	processes, err := store.ListProcesses(currentContext)
+
+	if err != nil {
+		return fmt.Errorf("failed to list running proxies: %w", err)
+	}
+
+	stopped := 0
+
+	// Stopping the supervisor shuts its proxies down, stray proxies of a
+	// supervisor that died are stopped directly
+	for _, process := range processes {
+		if process.Workspace != name {
+			continue
+		}
+
+		err = process.Stop()
+
+		if err != nil {
+			logger.Warn().
+				Err(err).
+				Int("pid", process.PID).
+				Str(process.Kind, process.Name).
+				Msg("Failed to stop process")
+
+			continue
+		}
+
+		stopped++
+	}
+
+	if stopped == 0 {
+		logger.Info().
+			Str("workspace", name).
+			Msg("Workspace is not running")
+
+		return nil
+	}
+
+	logger.Info().
+		Str("workspace", name).
+		Msg("Workspace stopped")
+
+	return nil
+}
+
+// Proxies resolves the members of a workspace into proxies, ordered in
+// stages so tunnels are ready before databases and databases before apps
+func Proxies(configContext store.Context, workspace store.Workspace) ([]supervisor.Proxy, error) {
+	var proxies []supervisor.Proxy
+
+	for _, alias := range workspace.Tunnels {
+		host, ok := configContext.Hosts[alias]
+
+		if !ok {
+			return nil, fmt.Errorf("ssh host '%s' of workspace '%s' not found", alias, workspace.Name)
+		}
+
+		if len(host.Forwards) == 0 {
+			return nil, fmt.Errorf("ssh host '%s' of workspace '%s' has no port forwards", alias, workspace.Name)
+		}
+
+		proxy := ssh.Proxy(host)
+		proxy.Stage = stageTunnels
+		proxies = append(proxies, proxy)
+	}
+
+	for _, tunnel := range workspace.Databases {
+		db, ok := configContext.Database[tunnel]
+
+		if !ok {
+			return nil, fmt.Errorf("database '%s' of workspace '%s' not found", tunnel, workspace.Name)
+		}
+
+		proxy := database.Proxy(db)
+		proxy.Stage = stageDatabases
+		proxies = append(proxies, proxy)
+	}
+
+	for _, name := range workspace.Apps {
+		configApp, ok := configContext.Apps[name]
+
+		if !ok {
+			return nil, fmt.Errorf("application '%s' of workspace '%s' not found", name, workspace.Name)
+		}
+
+		proxy := app.Proxy(configApp)
+		proxy.Stage = stageApps
+		proxies = append(proxies, proxy)
+	}
+
+	for i := range proxies {
+		proxies[i].Workspace = workspace.Name
+	}
+
+	return proxies, nil
+}
+
+func findWorkspace(configContext store.Context, name string) (store.Workspace, error) {
+	workspace, ok := configContext.Workspaces[name]
+
+	if !ok {
+		return store.Workspace{}, exit.New(exit.Failure,
+			fmt.Errorf("workspace '%s' not found", name),
+			"Run 'paycast workspace list' to see workspaces")
+	}
+
+	return workspace, nil
+}
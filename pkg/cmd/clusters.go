@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/RiskyFeryansyahP/paycast/pkg/dryrun"
+)
+
+// ClusterInfo is a cluster as reported by `tsh clusters --format=json`
+type ClusterInfo struct {
+	Name     string            `json:"cluster_name"`
+	Status   string            `json:"status"`
+	Type     string            `json:"cluster_type"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	Selected bool              `json:"selected"`
+}
+
+// Clusters lists the root cluster and the leaf clusters trusting it
+func Clusters(ctx context.Context, home string) ([]ClusterInfo, error) {
+	output, err := Command(ctx, home, "clusters", "--format=json").Output()
+
+	if err != nil {
+		return nil, err
+	}
+
+	// A dry run ran nothing, there is no output to decode
+	if dryrun.Enabled() {
+		return nil, nil
+	}
+
+	var clusters []ClusterInfo
+
+	err = json.Unmarshal(output, &clusters)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return clusters, nil
+}
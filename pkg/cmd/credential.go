@@ -0,0 +1,203 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/RiskyFeryansyahP/paycast/internal/store"
+)
+
+const (
+	// CredentialsFile is the file read by the built-in "file" helper, relative
+	// to the paycast configuration directory
+	CredentialsFile = "credentials"
+
+	credentialHelperTimeout = 30 * time.Second
+)
+
+// ErrNoCredential is returned when a credential helper has no secret for a request
+var ErrNoCredential = errors.New("credential helper returned no secret")
+
+// CredentialRequest describes the secret a credential helper is asked for.
+// Kind is the kind of prompt, e.g. password, otp or pin
+type CredentialRequest struct {
+	Context string
+	Proxy   string
+	User    string
+	Kind    string
+	Prompt  string
+}
+
+// Encode writes the request in the key=value format read by helpers,
+// terminated by a blank line
+func (r CredentialRequest) Encode() []byte {
+	var buf bytes.Buffer
+
+	fields := [][2]string{
+		{"context", r.Context},
+		{"proxy", r.Proxy},
+		{"user", r.User},
+		{"kind", r.Kind},
+		{"prompt", r.Prompt},
+	}
+
+	for _, field := range fields {
+		value := strings.ReplaceAll(field[1], "\n", " ")
+		fmt.Fprintf(&buf, "%s=%s\n", field[0], strings.TrimSpace(value))
+	}
+
+	buf.WriteString("\n")
+
+	return buf.Bytes()
+}
+
+// RequestCredential asks the credential helper for a secret. The helper is
+// either a built-in ("env", "file" or "file:<path>") or a shell command that
+// receives the request on stdin and prints "secret=<value>" on stdout
+func RequestCredential(ctx context.Context, helper string, req CredentialRequest) (string, error) {
+	switch {
+	case helper == "env":
+		return envCredential(req)
+	case helper == "file":
+		homeDir, err := os.UserHomeDir()
+
+		if err != nil {
+			return "", err
+		}
+
+		return fileCredential(filepath.Join(homeDir, store.DIR, CredentialsFile), req)
+	case strings.HasPrefix(helper, "file:"):
+		return fileCredential(strings.TrimPrefix(helper, "file:"), req)
+	}
+
+	return commandCredential(ctx, helper, req)
+}
+
+// envCredential reads PAYCAST_<CONTEXT>_<KIND>, falling back to PAYCAST_<KIND>
+func envCredential(req CredentialRequest) (string, error) {
+	kind := EnvName(req.Kind)
+
+	names := []string{"PAYCAST_" + kind}
+
+	if req.Context != "" {
+		names = append([]string{"PAYCAST_" + EnvName(req.Context) + "_" + kind}, names...)
+	}
+
+	for _, name := range names {
+		value, ok := os.LookupEnv(name)
+
+		if ok && value != "" {
+			return value, nil
+		}
+	}
+
+	return "", ErrNoCredential
+}
+
+// fileCredential reads "<context>.<kind>=<secret>" or "<kind>=<secret>" lines
+// from a file only readable by its owner
+func fileCredential(path string, req CredentialRequest) (string, error) {
+	info, err := os.Stat(path)
+
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", ErrNoCredential
+		}
+
+		return "", err
+	}
+
+	if info.Mode().Perm()&0077 != 0 {
+		return "", fmt.Errorf("credentials file %s must not be accessible by other users, run 'chmod 600 %s'", path, path)
+	}
+
+	data, err := os.ReadFile(path)
+
+	if err != nil {
+		return "", err
+	}
+
+	values := parseKeyValues(data)
+
+	secret, ok := values[req.Context+"."+req.Kind]
+
+	if !ok {
+		secret, ok = values[req.Kind]
+	}
+
+	if !ok || secret == "" {
+		return "", ErrNoCredential
+	}
+
+	return secret, nil
+}
+
+func commandCredential(ctx context.Context, helper string, req CredentialRequest) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, credentialHelperTimeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", helper)
+	cmd.Stdin = bytes.NewReader(req.Encode())
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+
+	if err != nil {
+		return "", fmt.Errorf("credential helper failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	secret := parseKeyValues(stdout.Bytes())["secret"]
+
+	if secret == "" {
+		return "", ErrNoCredential
+	}
+
+	return secret, nil
+}
+
+func parseKeyValues(data []byte) map[string]string {
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+
+		if !ok {
+			continue
+		}
+
+		values[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+
+	return values
+}
+
+// EnvName turns s into the part of an environment variable name, upper case
+// with every character other than a letter or digit replaced by '_'
+func EnvName(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToUpper(r)
+		}
+
+		return '_'
+	}, s)
+}
@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"errors"
+	"maps"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseKeyValues(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want map[string]string
+	}{
+		{
+			name: "empty",
+			data: "",
+			want: map[string]string{},
+		},
+		{
+			name: "pairs with spaces",
+			data: "secret = hunter2\nkind=otp\n",
+			want: map[string]string{"secret": "hunter2", "kind": "otp"},
+		},
+		{
+			name: "comments, blanks and lines without =",
+			data: "# staging\n\nnot a pair\nstaging.password=s3cret\n",
+			want: map[string]string{"staging.password": "s3cret"},
+		},
+		{
+			name: "value containing =",
+			data: "secret=a=b\n",
+			want: map[string]string{"secret": "a=b"},
+		},
+		{
+			name: "last value wins",
+			data: "secret=one\nsecret=two\n",
+			want: map[string]string{"secret": "two"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseKeyValues([]byte(tt.data))
+
+			if !maps.Equal(got, tt.want) {
+				t.Errorf("parseKeyValues(%q) = %v, want %v", tt.data, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFileCredential(t *testing.T) {
+	dir := t.TempDir()
+
+	private := filepath.Join(dir, "credentials")
+	writeFile(t, private, "staging.password=stg-secret\npassword=default-secret\nprod.otp=\n", 0600)
+
+	shared := filepath.Join(dir, "shared")
+	writeFile(t, shared, "password=secret\n", 0644)
+
+	tests := []struct {
+		name    string
+		path    string
+		req     CredentialRequest
+		want    string
+		wantErr error
+	}{
+		{
+			name: "context specific secret",
+			path: private,
+			req:  CredentialRequest{Context: "staging", Kind: "password"},
+			want: "stg-secret",
+		},
+		{
+			name: "falls back to the kind",
+			path: private,
+			req:  CredentialRequest{Context: "dev", Kind: "password"},
+			want: "default-secret",
+		},
+		{
+			name:    "empty secret",
+			path:    private,
+			req:     CredentialRequest{Context: "prod", Kind: "otp"},
+			wantErr: ErrNoCredential,
+		},
+		{
+			name:    "unknown kind",
+			path:    private,
+			req:     CredentialRequest{Context: "staging", Kind: "pin"},
+			wantErr: ErrNoCredential,
+		},
+		{
+			name:    "missing file",
+			path:    filepath.Join(dir, "missing"),
+			req:     CredentialRequest{Kind: "password"},
+			wantErr: ErrNoCredential,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := fileCredential(tt.path, tt.req)
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("fileCredential() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("fileCredential() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("readable by other users", func(t *testing.T) {
+		_, err := fileCredential(shared, CredentialRequest{Kind: "password"})
+
+		if err == nil || errors.Is(err, ErrNoCredential) {
+			t.Errorf("fileCredential() error = %v, want a permission error", err)
+		}
+	})
+}
+
+func writeFile(t *testing.T, path string, data string, perm os.FileMode) {
+	t.Helper()
+
+	err := os.WriteFile(path, []byte(data), perm)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// WriteFile is subject to the umask
+	err = os.Chmod(path, perm)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+}
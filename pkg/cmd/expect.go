@@ -0,0 +1,230 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"io"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	// DefaultPromptTimeout is how long Login waits for tsh to print something
+	// before giving up on a stalled session
+	DefaultPromptTimeout = 2 * time.Minute
+
+	// maxExpectBuffer bounds the unmatched output kept around for matching
+	maxExpectBuffer = 8192
+)
+
+// ErrExpectTimeout is returned when no output matched before the timeout
+var ErrExpectTimeout = errors.New("timed out waiting for tsh prompt")
+
+var (
+	ansiEscape   = regexp.MustCompile(`\x1b\[[0-9;?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(?:\x07|\x1b\\)|\x1b[@-Z\\-_]`)
+	ansiComplete = regexp.MustCompile(`^(?:` + ansiEscape.String() + `)`)
+)
+
+// ExpectRule names a pattern to look for in the output of a process
+type ExpectRule struct {
+	Name    string
+	Pattern *regexp.Regexp
+}
+
+// Match describes the rule that matched and the text around it
+type Match struct {
+	Rule   ExpectRule
+	Text   string
+	Groups []string
+	Before string
+}
+
+// Expecter reads process output as it arrives and matches rules against
+// partial output, so prompts that don't end with a newline are still seen
+type Expecter struct {
+	echo    io.Writer
+	chunks  chan []byte
+	done    chan struct{}
+	err     error
+	buf     string
+	pending []byte
+}
+
+// NewExpecter starts reading r in the background. Everything read is
+// written to echo with ANSI escapes removed, echo may be nil
+func NewExpecter(r io.Reader, echo io.Writer) *Expecter {
+	e := &Expecter{
+		echo:   echo,
+		chunks: make(chan []byte),
+		done:   make(chan struct{}),
+	}
+
+	go e.read(r)
+
+	return e
+}
+
+func (e *Expecter) read(r io.Reader) {
+	defer close(e.chunks)
+
+	for {
+		data := make([]byte, 1024)
+		n, err := r.Read(data)
+
+		if n > 0 {
+			select {
+			case e.chunks <- data[:n]:
+			case <-e.done:
+				return
+			}
+		}
+
+		if err != nil {
+			// Linux returns EIO from the pty master once the child has exited
+			if errors.Is(err, syscall.EIO) {
+				err = io.EOF
+			}
+
+			e.err = err
+			return
+		}
+	}
+}
+
+// Expect waits until one of the rules matches the output that has not been
+// consumed yet. The earliest match in the output wins, ties go to the rule
+// listed first. A zero timeout waits forever, otherwise the timeout is reset
+// every time new output arrives. io.EOF is returned once the output ends
+// without a match
+func (e *Expecter) Expect(ctx context.Context, timeout time.Duration, rules ...ExpectRule) (*Match, error) {
+	var timer <-chan time.Time
+
+	for {
+		match := e.match(rules)
+
+		if match != nil {
+			return match, nil
+		}
+
+		if timeout > 0 {
+			timer = time.After(timeout)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-timer:
+			return nil, ErrExpectTimeout
+		case data, ok := <-e.chunks:
+			if !ok {
+				e.append(nil, true)
+
+				match := e.match(rules)
+
+				if match != nil {
+					return match, nil
+				}
+
+				if e.err != nil {
+					return nil, e.err
+				}
+
+				return nil, io.EOF
+			}
+
+			e.append(data, false)
+		}
+	}
+}
+
+// Close stops the background reader. The underlying reader is not closed
+func (e *Expecter) Close() {
+	select {
+	case <-e.done:
+	default:
+		close(e.done)
+	}
+}
+
+// Output returns the output read so far that no rule has consumed
+func (e *Expecter) Output() string {
+	return e.buf
+}
+
+func (e *Expecter) append(data []byte, flush bool) {
+	raw := append(e.pending, data...)
+	e.pending = nil
+
+	// Hold back a trailing escape sequence that may continue in the next chunk
+	idx := strings.LastIndexByte(string(raw), '\x1b')
+
+	if !flush && idx >= 0 && len(raw)-idx < 32 && !ansiComplete.Match(raw[idx:]) {
+		e.pending = append([]byte(nil), raw[idx:]...)
+		raw = raw[:idx]
+	}
+
+	text := CleanOutput(string(raw))
+
+	if text == "" {
+		return
+	}
+
+	if e.echo != nil {
+		_, _ = io.WriteString(e.echo, text)
+	}
+
+	e.buf += text
+
+	if len(e.buf) > maxExpectBuffer {
+		e.buf = e.buf[len(e.buf)-maxExpectBuffer:]
+	}
+}
+
+func (e *Expecter) match(rules []ExpectRule) *Match {
+	var best *Match
+	bestStart, bestEnd := -1, -1
+
+	for _, rule := range rules {
+		loc := rule.Pattern.FindStringSubmatchIndex(e.buf)
+
+		// Zero width matches consume nothing and would match again forever
+		if loc == nil || loc[0] == loc[1] || (bestStart >= 0 && loc[0] >= bestStart) {
+			continue
+		}
+
+		groups := make([]string, 0, len(loc)/2)
+
+		for i := 0; i < len(loc); i += 2 {
+			if loc[i] < 0 {
+				groups = append(groups, "")
+				continue
+			}
+
+			groups = append(groups, e.buf[loc[i]:loc[i+1]])
+		}
+
+		best = &Match{
+			Rule:   rule,
+			Text:   e.buf[loc[0]:loc[1]],
+			Groups: groups,
+			Before: e.buf[:loc[0]],
+		}
+		bestStart, bestEnd = loc[0], loc[1]
+	}
+
+	if best != nil {
+		e.buf = e.buf[bestEnd:]
+	}
+
+	return best
+}
+
+// CleanOutput removes ANSI escape sequences and carriage returns from
+// terminal output
+func CleanOutput(s string) string {
+	s = ansiEscape.ReplaceAllString(s, "")
+
+	return strings.ReplaceAll(s, "\r", "")
+}
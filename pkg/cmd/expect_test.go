@@ -0,0 +1,186 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestExpecterExpect(t *testing.T) {
+	password := ExpectRule{Name: "password", Pattern: regexp.MustCompile(`(?i)[^\n]*password[^\n]*:\s*$`)}
+	otp := ExpectRule{Name: "otp", Pattern: regexp.MustCompile(`(?i)[^\n]*otp[^\n]*:\s*$`)}
+
+	tests := []struct {
+		name     string
+		chunks   []string
+		close    bool
+		timeout  time.Duration
+		rules    []ExpectRule
+		wantRule string
+		wantText string
+		wantEcho string
+		wantErr  error
+	}{
+		{
+			name:     "prompt without trailing newline",
+			chunks:   []string{"Enter password for Teleport user alice:"},
+			rules:    []ExpectRule{password},
+			wantRule: "password",
+			wantText: "Enter password for Teleport user alice:",
+			wantEcho: "Enter password for Teleport user alice:",
+		},
+		{
+			name:     "prompt split across reads",
+			chunks:   []string{"Enter pass", "word: "},
+			rules:    []ExpectRule{password},
+			wantRule: "password",
+			wantText: "Enter password: ",
+			wantEcho: "Enter password: ",
+		},
+		{
+			name:     "ansi escape split across reads",
+			chunks:   []string{"\x1b[3", "1mEnter pass\x1b[0", "mword:"},
+			rules:    []ExpectRule{password},
+			wantRule: "password",
+			wantText: "Enter password:",
+			wantEcho: "Enter password:",
+		},
+		{
+			name:     "earliest match wins",
+			chunks:   []string{"Enter your OTP token:"},
+			rules:    []ExpectRule{password, otp},
+			wantRule: "otp",
+			wantText: "Enter your OTP token:",
+			wantEcho: "Enter your OTP token:",
+		},
+		{
+			name:     "timeout",
+			chunks:   []string{"Connecting to proxy...\n"},
+			timeout:  50 * time.Millisecond,
+			rules:    []ExpectRule{password},
+			wantEcho: "Connecting to proxy...\n",
+			wantErr:  ErrExpectTimeout,
+		},
+		{
+			name:     "eof without match",
+			chunks:   []string{"Logged in as alice\n"},
+			close:    true,
+			rules:    []ExpectRule{password},
+			wantEcho: "Logged in as alice\n",
+			wantErr:  io.EOF,
+		},
+		{
+			name:     "zero width match ignored",
+			chunks:   []string{"Welcome\n", "Enter password:"},
+			rules:    []ExpectRule{{Name: "banner", Pattern: regexp.MustCompile(`(?i)(goodbye)?`)}, password},
+			wantRule: "password",
+			wantText: "Enter password:",
+			wantEcho: "Welcome\nEnter password:",
+		},
+		{
+			name:     "incomplete escape held back",
+			chunks:   []string{"Enter password:\x1b["},
+			close:    true,
+			rules:    []ExpectRule{password},
+			wantRule: "password",
+			wantText: "Enter password:",
+			wantEcho: "Enter password:",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, w := io.Pipe()
+			defer w.Close()
+
+			go func() {
+				for _, chunk := range tt.chunks {
+					_, err := w.Write([]byte(chunk))
+
+					if err != nil {
+						return
+					}
+				}
+
+				if tt.close {
+					w.Close()
+				}
+			}()
+
+			var echo bytes.Buffer
+
+			expecter := NewExpecter(r, &echo)
+			defer expecter.Close()
+
+			timeout := tt.timeout
+
+			if timeout == 0 {
+				timeout = 5 * time.Second
+			}
+
+			match, err := expecter.Expect(context.Background(), timeout, tt.rules...)
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Expect() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr == nil {
+				if match.Rule.Name != tt.wantRule {
+					t.Errorf("Expect() rule = %q, want %q", match.Rule.Name, tt.wantRule)
+				}
+
+				if match.Text != tt.wantText {
+					t.Errorf("Expect() text = %q, want %q", match.Text, tt.wantText)
+				}
+			}
+
+			if echo.String() != tt.wantEcho {
+				t.Errorf("echo = %q, want %q", echo.String(), tt.wantEcho)
+			}
+		})
+	}
+}
+
+func TestExpecterExpectCanceled(t *testing.T) {
+	r, w := io.Pipe()
+	defer w.Close()
+
+	expecter := NewExpecter(r, nil)
+	defer expecter.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := expecter.Expect(ctx, 0, ExpectRule{Name: "any", Pattern: regexp.MustCompile(`.`)})
+
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expect() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestCleanOutput(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "plain", in: "Logged in\n", want: "Logged in\n"},
+		{name: "color", in: "\x1b[1;32mok\x1b[0m", want: "ok"},
+		{name: "carriage return", in: "line\r\n", want: "line\n"},
+		{name: "title", in: "\x1b]0;tsh\x07prompt", want: "prompt"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CleanOutput(tt.in)
+
+			if got != tt.want {
+				t.Errorf("CleanOutput(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
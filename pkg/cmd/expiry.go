@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/RiskyFeryansyahP/paycast/pkg/logger"
+)
+
+// expiryTolerance is how far the absolute and relative expiry printed by tsh
+// may disagree, the relative duration is rounded by tsh
+const expiryTolerance = 2 * time.Minute
+
+// validUntilPattern matches the value tsh prints after "Valid until:", e.g.
+// "2024-05-01 19:04:05 +0700 WIB [valid for 11h59m0s]"
+var validUntilPattern = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2})(?: ([+-]\d{4}))?(?: ([A-Za-z][A-Za-z0-9+-]*))?(?: \[(?:valid for ([^\]]+)|(EXPIRED))\])?`)
+
+// ParseValidUntil parses the session expiry printed by tsh including its
+// zone and cross-checks it against the "[valid for Xh]" duration relative to
+// now. When both disagree the relative duration wins, since it does not
+// depend on the zone being understood. The result is in UTC
+func ParseValidUntil(value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	parts := validUntilPattern.FindStringSubmatch(value)
+
+	if parts == nil {
+		return time.Time{}, fmt.Errorf("unrecognized session expiry '%s'", value)
+	}
+
+	timestamp, offset, zone, validFor, expired := parts[1], parts[2], parts[3], parts[4], parts[5]
+
+	var absolute time.Time
+	var err error
+
+	switch {
+	case offset != "":
+		absolute, err = time.Parse("2006-01-02 15:04:05 -0700", timestamp+" "+offset)
+	case zone != "":
+		absolute, err = parseInZone(timestamp, zone)
+	default:
+		absolute, err = time.ParseInLocation(time.DateTime, timestamp, time.Local)
+	}
+
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid session expiry '%s': %w", value, err)
+	}
+
+	if expired != "" && absolute.After(now) {
+		logger.Warn().
+			Str("expiry", value).
+			Msg("tsh reports the session as expired, ignoring its expiry time")
+
+		return now.UTC(), nil
+	}
+
+	if validFor == "" {
+		return absolute.UTC(), nil
+	}
+
+	duration, err := time.ParseDuration(strings.ReplaceAll(validFor, " ", ""))
+
+	if err != nil {
+		return absolute.UTC(), nil
+	}
+
+	relative := now.Add(duration)
+
+	if diff := absolute.Sub(relative); diff > expiryTolerance || diff < -expiryTolerance {
+		logger.Warn().
+			Time("absolute", absolute.Local()).
+			Time("relative", relative.Local()).
+			Msg("Session expiry and remaining validity reported by tsh disagree, using remaining validity")
+
+		return relative.UTC(), nil
+	}
+
+	return absolute.UTC(), nil
+}
+
+// parseInZone parses a timestamp in a zone given only by its abbreviation.
+// Abbreviations are ambiguous, so it is only trusted when it is the local
+// zone or UTC at that time
+func parseInZone(timestamp string, zone string) (time.Time, error) {
+	if zone == "UTC" || zone == "GMT" {
+		return time.ParseInLocation(time.DateTime, timestamp, time.UTC)
+	}
+
+	local, err := time.ParseInLocation(time.DateTime, timestamp, time.Local)
+
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	name, _ := local.Zone()
+
+	if name != zone {
+		return time.Time{}, fmt.Errorf("unknown time zone '%s'", zone)
+	}
+
+	return local, nil
+}
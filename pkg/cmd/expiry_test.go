@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestParseValidUntil(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		value   string
+		want    time.Time
+		wantErr bool
+	}{
+		{
+			name:  "offset and zone agreeing with validity",
+			value: "2024-05-01 23:59:00 +0700 WIB [valid for 4h59m0s]",
+			want:  time.Date(2024, 5, 1, 16, 59, 0, 0, time.UTC),
+		},
+		{
+			name:  "validity within tolerance",
+			value: "2024-05-01 23:59:00 +0700 WIB [valid for 5h0m0s]",
+			want:  time.Date(2024, 5, 1, 16, 59, 0, 0, time.UTC),
+		},
+		{
+			name:  "validity disagreeing wins",
+			value: "2024-05-01 23:59:00 +0700 [valid for 2h0m0s]",
+			want:  time.Date(2024, 5, 1, 14, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "utc abbreviation",
+			value: "2024-05-01 18:00:00 UTC",
+			want:  time.Date(2024, 5, 1, 18, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "surrounding whitespace",
+			value: "  2024-05-01 18:00:00 +0000 UTC\n",
+			want:  time.Date(2024, 5, 1, 18, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "expired in the past",
+			value: "2024-05-01 10:00:00 +0000 UTC [EXPIRED]",
+			want:  time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "expired with a future time",
+			value: "2024-05-01 18:00:00 +0000 UTC [EXPIRED]",
+			want:  now,
+		},
+		{
+			name:    "unknown zone abbreviation",
+			value:   "2024-05-01 18:00:00 ZZZ",
+			wantErr: true,
+		},
+		{
+			name:    "unrecognized",
+			value:   "tomorrow",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseValidUntil(tt.value, now)
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseValidUntil(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			if !got.Equal(tt.want) || got.Location() != time.UTC {
+				t.Errorf("ParseValidUntil(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseStatusText(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	output := "\x1b[1m> Profile URL:        https://teleport.example.com:443\x1b[0m\r\n" +
+		"  Logged in as:       alice\r\n" +
+		"  Cluster:            example\r\n" +
+		"  Roles:              access, editor\r\n" +
+		"  Logins:             alice, root\r\n" +
+		"  Valid until:        2024-05-01 20:00:00 +0000 UTC [valid for 8h0m0s]\r\n" +
+		"\r\n" +
+		"  Profile URL:        https://other.example.com:443\r\n" +
+		"  Logged in as:       bob\r\n"
+
+	tests := []struct {
+		name    string
+		output  string
+		want    ProfileStatus
+		wantErr error
+	}{
+		{
+			name:   "first profile",
+			output: output,
+			want: ProfileStatus{
+				ProxyURL:   "https://teleport.example.com:443",
+				Username:   "alice",
+				Cluster:    "example",
+				Roles:      []string{"access", "editor"},
+				Logins:     []string{"alice", "root"},
+				ValidUntil: time.Date(2024, 5, 1, 20, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:    "not logged in",
+			output:  "Not logged in.\n",
+			wantErr: ErrNotLoggedIn,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseStatusText(tt.output, now)
+
+			if err != tt.wantErr {
+				t.Fatalf("parseStatusText() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil {
+				return
+			}
+
+			if got.ProxyURL != tt.want.ProxyURL || got.Username != tt.want.Username || got.Cluster != tt.want.Cluster {
+				t.Errorf("parseStatusText() = %+v, want %+v", got, tt.want)
+			}
+
+			if !slices.Equal(got.Roles, tt.want.Roles) || !slices.Equal(got.Logins, tt.want.Logins) {
+				t.Errorf("parseStatusText() roles %v logins %v, want %v %v", got.Roles, got.Logins, tt.want.Roles, tt.want.Logins)
+			}
+
+			if !got.ValidUntil.Equal(tt.want.ValidUntil) {
+				t.Errorf("parseStatusText() valid until = %v, want %v", got.ValidUntil, tt.want.ValidUntil)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+
+	"github.com/RiskyFeryansyahP/paycast/pkg/dryrun"
+	"github.com/creack/pty"
+	"golang.org/x/term"
+)
+
+// RunInteractive runs the command in a pseudo terminal wired to the terminal
+// of the user, forwarding input, output and window size changes until the
+// command exits
+func RunInteractive(c *exec.Cmd) error {
+	if dryrun.Enabled() {
+		return nil
+	}
+
+	// Interactive programs need the real terminal type rather than the dumb
+	// one used when paycast parses tsh output
+	if terminal := os.Getenv("TERM"); terminal != "" {
+		c.Env = append(c.Env, "TERM="+terminal)
+	}
+
+	ptyF, err := pty.Start(c)
+
+	if err != nil {
+		return err
+	}
+	defer ptyF.Close()
+
+	resize := make(chan os.Signal, 1)
+	signal.Notify(resize, syscall.SIGWINCH)
+	defer signal.Stop(resize)
+
+	go func() {
+		for range resize {
+			_ = pty.InheritSize(os.Stdin, ptyF)
+		}
+	}()
+	resize <- syscall.SIGWINCH
+
+	stdin := int(os.Stdin.Fd())
+
+	if term.IsTerminal(stdin) {
+		state, err := term.MakeRaw(stdin)
+
+		if err != nil {
+			return err
+		}
+		defer term.Restore(stdin, state)
+	}
+
+	go func() {
+		_, _ = io.Copy(ptyF, os.Stdin)
+	}()
+
+	_, _ = io.Copy(os.Stdout, ptyF)
+
+	return c.Wait()
+}
@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/RiskyFeryansyahP/paycast/internal/store"
+	"github.com/RiskyFeryansyahP/paycast/pkg/dryrun"
+	"github.com/RiskyFeryansyahP/paycast/pkg/logger"
+)
+
+// KubeCluster is a Kubernetes cluster as reported by `tsh kube ls --format=json`
+type KubeCluster struct {
+	Name     string            `json:"kube_cluster_name"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	Selected bool              `json:"selected"`
+}
+
+// KubeClusters lists the Kubernetes clusters the context can access
+func KubeClusters(ctx context.Context, configContext store.Context) ([]KubeCluster, error) {
+	output, err := Command(ctx, configContext.TeleportHome(), "kube", "ls", "--format=json").Output()
+
+	if err != nil {
+		return nil, err
+	}
+
+	// A dry run ran nothing, there is no output to decode
+	if dryrun.Enabled() {
+		return nil, nil
+	}
+
+	var clusters []KubeCluster
+
+	err = json.Unmarshal(output, &clusters)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return clusters, nil
+}
+
+// KubeLogin writes credentials for a Kubernetes cluster into the kubeconfig
+// of the context, leaving ~/.kube/config untouched
+func KubeLogin(ctx context.Context, configContext store.Context, cluster string) error {
+	kubeconfig := configContext.Kubeconfig()
+
+	if !dryrun.Enabled() {
+		err := os.MkdirAll(filepath.Dir(kubeconfig), 0700)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	cmd := Command(ctx, configContext.TeleportHome(), "kube", "login", cluster)
+	cmd.Env = append(cmd.Env, "KUBECONFIG="+kubeconfig)
+
+	output, err := cmd.CombinedOutput()
+
+	if err != nil {
+		return fmt.Errorf("tsh kube login failed: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+
+	return nil
+}
+
+// RefreshKube logs in again to every Kubernetes cluster of the context, the
+// selected cluster last so it stays the current kubeconfig context
+func RefreshKube(ctx context.Context, configContext store.Context) {
+	clusters := make([]string, 0, len(configContext.KubeClusters))
+
+	for _, cluster := range configContext.KubeClusters {
+		if cluster != configContext.KubeCluster {
+			clusters = append(clusters, cluster)
+		}
+	}
+
+	if configContext.KubeCluster != "" {
+		clusters = append(clusters, configContext.KubeCluster)
+	}
+
+	for _, cluster := range clusters {
+		err := KubeLogin(ctx, configContext, cluster)
+
+		if err != nil {
+			logger.Warn().
+				Err(err).
+				Str("kube", cluster).
+				Msg("Failed to refresh kubeconfig")
+
+			continue
+		}
+
+		logger.Debug().
+			Str("kube", cluster).
+			Str("kubeconfig", configContext.Kubeconfig()).
+			Msg("Kubeconfig refreshed")
+	}
+}
@@ -0,0 +1,233 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/RiskyFeryansyahP/paycast/internal/store"
+	"github.com/RiskyFeryansyahP/paycast/pkg/dryrun"
+	"github.com/RiskyFeryansyahP/paycast/pkg/logger"
+	"github.com/creack/pty"
+)
+
+// LoginSpec describes a single `tsh login` invocation
+type LoginSpec struct {
+	// Context is the paycast context being logged in, passed to credential helpers
+	Context string
+	// Home is the TELEPORT_HOME of the context, the global profile when empty
+	Home      string
+	Proxy     string
+	Auth      string
+	User      string
+	Cluster   string
+	TTL       time.Duration
+	ExtraArgs []string
+	// Prompts answers tsh prompts, DefaultPromptRules are used when empty
+	Prompts []PromptRule
+	// Browser is the command opening SSO login URLs. When set tsh is told
+	// not to open a browser itself, "none" only prints the URL
+	Browser string
+	// CredentialHelper supplies secrets for prompts before falling back to
+	// the terminal, see RequestCredential
+	CredentialHelper string
+	// SSOTimeout bounds the wait for an SSO login, DefaultSSOTimeout when zero
+	SSOTimeout time.Duration
+}
+
+// DefaultSSOTimeout is how long Login waits for the browser callback of an
+// SSO connector
+const DefaultSSOTimeout = 5 * time.Minute
+
+// ErrLoginCancelled is returned when the user interrupts a pending SSO login
+var ErrLoginCancelled = errors.New("login cancelled")
+
+// Args builds the tsh command line for the spec
+func (s LoginSpec) Args() []string {
+	args := []string{"login"}
+
+	if s.Proxy != "" {
+		args = append(args, fmt.Sprintf("--proxy=%s", s.Proxy))
+	}
+
+	if s.Auth != "" {
+		args = append(args, fmt.Sprintf("--auth=%s", s.Auth))
+	}
+
+	if s.User != "" {
+		args = append(args, fmt.Sprintf("--user=%s", s.User))
+	}
+
+	if s.Browser != "" {
+		args = append(args, "--browser=none")
+	}
+
+	if s.TTL > 0 {
+		args = append(args, fmt.Sprintf("--ttl=%d", int(s.TTL.Minutes())))
+	}
+
+	args = append(args, s.ExtraArgs...)
+
+	if s.Cluster != "" {
+		args = append(args, s.Cluster)
+	}
+
+	return args
+}
+
+// Session is the result of a successful login. A DryRun session has no
+// profile and leaves contexts unchanged
+type Session struct {
+	Spec    LoginSpec
+	Profile ProfileStatus
+	DryRun  bool
+}
+
+// Apply copies the session details into the given context
+func (s *Session) Apply(configContext *store.Context) {
+	if s.DryRun {
+		return
+	}
+
+	s.Profile.Apply(configContext)
+}
+
+// Login runs tsh login for the given spec, answering its prompts from the
+// terminal, and returns the active profile once tsh exits
+func Login(ctx context.Context, spec LoginSpec) (*Session, error) {
+	if dryrun.Enabled() {
+		Command(ctx, spec.Home, spec.Args()...)
+
+		return &Session{Spec: spec, DryRun: true}, nil
+	}
+
+	if spec.Home != "" {
+		err := os.MkdirAll(spec.Home, 0700)
+
+		if err != nil {
+			logger.Error().
+				Err(err).
+				Str("path", spec.Home).
+				Msg("Failed to create tsh home directory for context")
+
+			return nil, err
+		}
+	}
+
+	cmd := Command(ctx, spec.Home, spec.Args()...)
+
+	ptyF, err := pty.Start(cmd)
+
+	if err != nil {
+		logger.Error().
+			Err(err).
+			Msg("Failed to start terminal session for tsh login")
+
+		return nil, err
+	}
+	defer ptyF.Close()
+
+	prompts := spec.Prompts
+
+	if len(prompts) == 0 {
+		prompts = DefaultPromptRules
+	}
+
+	expectRules := make([]ExpectRule, 0, len(prompts))
+	promptByName := make(map[string]PromptRule, len(prompts))
+
+	for _, prompt := range prompts {
+		expectRules = append(expectRules, ExpectRule{Name: prompt.Name, Pattern: prompt.Pattern})
+		promptByName[prompt.Name] = prompt
+	}
+
+	// The login conversation goes to stderr, stdout is kept for command results
+	expecter := NewExpecter(ptyF, os.Stderr)
+	defer expecter.Close()
+
+	waitCtx, timeout := ctx, DefaultPromptTimeout
+
+	for {
+		match, err := expecter.Expect(waitCtx, timeout, expectRules...)
+
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if waitCtx != ctx && waitCtx.Err() != nil && ctx.Err() == nil {
+			err = ErrLoginCancelled
+		}
+
+		if waitCtx != ctx && errors.Is(err, ErrExpectTimeout) {
+			err = fmt.Errorf("no SSO login completed in the browser: %w", err)
+		}
+
+		if err != nil {
+			logger.Error().
+				Err(err).
+				Msg("Failed waiting for tsh login prompt")
+
+			_ = cmd.Process.Kill()
+			_ = cmd.Wait()
+
+			return nil, err
+		}
+
+		prompt := promptByName[match.Rule.Name]
+		err = prompt.answer(ctx, spec, ptyF, match)
+
+		if err != nil {
+			logger.Error().
+				Err(err).
+				Str("prompt", match.Rule.Name).
+				Msg("Failed to answer tsh login prompt")
+
+			_ = cmd.Process.Kill()
+			_ = cmd.Wait()
+
+			return nil, err
+		}
+
+		// tsh now blocks until the browser callback arrives, allow the user
+		// to give up with Ctrl-C instead of waiting for the timeout
+		if prompt.Action == ActionBrowser && waitCtx == ctx {
+			var stop context.CancelFunc
+
+			waitCtx, stop = signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			timeout = spec.SSOTimeout
+
+			if timeout <= 0 {
+				timeout = DefaultSSOTimeout
+			}
+		}
+	}
+
+	err = cmd.Wait()
+
+	if err != nil {
+		logger.Error().
+			Err(err).
+			Msg("tsh login command failed. Please check your credentials and try again")
+
+		return nil, err
+	}
+
+	status, err := FetchStatus(ctx, spec.Home)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if status.Active == nil {
+		return nil, ErrNotLoggedIn
+	}
+
+	return &Session{Spec: spec, Profile: *status.Active}, nil
+}
@@ -0,0 +1,278 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"syscall"
+
+	"github.com/RiskyFeryansyahP/paycast/internal/store"
+	"github.com/RiskyFeryansyahP/paycast/pkg/logger"
+	"golang.org/x/term"
+)
+
+// PromptAction is what Login does when a prompt rule matches
+type PromptAction string
+
+const (
+	// ActionSecret reads a value from the terminal without echo
+	ActionSecret PromptAction = "secret"
+	// ActionInput reads a visible line from the terminal
+	ActionInput PromptAction = "input"
+	// ActionChoose answers with the rule option, or asks the user when unset
+	ActionChoose PromptAction = "choose"
+	// ActionNotify tells the user to act outside the terminal and keeps waiting
+	ActionNotify PromptAction = "notify"
+	// ActionFail aborts the login
+	ActionFail PromptAction = "fail"
+	// ActionBrowser shows an SSO login URL and opens it with the configured
+	// browser command
+	ActionBrowser PromptAction = "browser"
+)
+
+// ErrNoTerminal is returned when a prompt needs input but paycast is not
+// attached to a terminal and no credential helper supplied it
+var ErrNoTerminal = errors.New("prompt needs input but stdin is not a terminal, configure a credential helper")
+
+// ErrPromptFailed is returned when a prompt rule with the fail action matches
+var ErrPromptFailed = errors.New("login aborted by prompt rule")
+
+// PromptRule maps a tsh prompt to the action answering it
+type PromptRule struct {
+	Name    string
+	Pattern *regexp.Regexp
+	Action  PromptAction
+	// Kind is passed to credential helpers for secret prompts, the rule
+	// name is used when empty
+	Kind    string
+	Option  string
+	Message string
+}
+
+// DefaultPromptRules are the built-in prompts tsh is known to print during
+// login. When several rules match, the one appearing first in the output
+// wins, ties go to the rule listed first. tsh introduces the SSO URL with
+// "Use the following URL to authenticate" when run with --browser=none
+var DefaultPromptRules = []PromptRule{
+	{
+		Name:    "sso",
+		Pattern: regexp.MustCompile(`(?i)(?:open it by clicking on the link|use the following URL to authenticate):\s*(https?://\S+)\s*\n`),
+		Action:  ActionBrowser,
+	},
+	{
+		Name:    "security-key-pin",
+		Pattern: regexp.MustCompile(`(?i)[^\n]*security key PIN[^\n]*:\s*$`),
+		Action:  ActionSecret,
+		Kind:    "pin",
+	},
+	{
+		Name:    "device",
+		Pattern: regexp.MustCompile(`(?i)[^\n]*(choose|select)[^\n]*(device|method|option)[^\n]*:\s*$`),
+		Action:  ActionChoose,
+	},
+	{
+		Name:    "otp",
+		Pattern: regexp.MustCompile(`(?i)[^\n]*(otp|one-time)[^\n]*:\s*$`),
+		Action:  ActionSecret,
+	},
+	{
+		Name:    "password",
+		Pattern: regexp.MustCompile(`(?i)[^\n]*password[^\n]*:\s*$`),
+		Action:  ActionSecret,
+	},
+	{
+		Name:    "security-key-tap",
+		Pattern: regexp.MustCompile(`(?i)[^\n]*tap (any|your)[^\n]*security key[^\n]*\n`),
+		Action:  ActionNotify,
+		Message: "Touch your security key to continue",
+	},
+}
+
+// PromptRules compiles the prompt overrides of a context on top of the
+// built-in rules. An override with the name of a built-in rule replaces it,
+// any other override is tried before the built-ins
+func PromptRules(overrides []store.PromptRule) ([]PromptRule, error) {
+	rules := make([]PromptRule, 0, len(overrides)+len(DefaultPromptRules))
+	replaced := make(map[string]bool)
+
+	for _, override := range overrides {
+		pattern, err := regexp.Compile(override.Pattern)
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern for prompt rule '%s': %w", override.Name, err)
+		}
+
+		// A pattern matching nothing consumes no output and would answer the
+		// same prompt forever
+		if pattern.MatchString("") {
+			return nil, fmt.Errorf("pattern for prompt rule '%s' matches empty output", override.Name)
+		}
+
+		action := PromptAction(override.Action)
+
+		switch action {
+		case ActionSecret, ActionInput, ActionChoose, ActionNotify, ActionFail, ActionBrowser:
+		default:
+			return nil, fmt.Errorf("unknown action '%s' for prompt rule '%s'", override.Action, override.Name)
+		}
+
+		rules = append(rules, PromptRule{
+			Name:    override.Name,
+			Pattern: pattern,
+			Action:  action,
+			Kind:    override.Kind,
+			Option:  override.Option,
+			Message: override.Message,
+		})
+		replaced[override.Name] = true
+	}
+
+	for _, rule := range DefaultPromptRules {
+		if !replaced[rule.Name] {
+			rules = append(rules, rule)
+		}
+	}
+
+	return rules, nil
+}
+
+var stdinReader = bufio.NewReader(os.Stdin)
+
+var urlPattern = regexp.MustCompile(`https?://\S+`)
+
+// answer performs the rule action for a matched prompt, writing any reply to w
+func (r PromptRule) answer(ctx context.Context, spec LoginSpec, w io.Writer, match *Match) error {
+	switch r.Action {
+	case ActionSecret:
+		if spec.CredentialHelper != "" {
+			kind := r.Kind
+
+			if kind == "" {
+				kind = r.Name
+			}
+
+			secret, err := RequestCredential(ctx, spec.CredentialHelper, CredentialRequest{
+				Context: spec.Context,
+				Proxy:   spec.Proxy,
+				User:    spec.User,
+				Kind:    kind,
+				Prompt:  strings.TrimSpace(match.Text),
+			})
+
+			if err == nil {
+				fmt.Fprintln(os.Stderr)
+				_, err = fmt.Fprintln(w, secret)
+
+				return err
+			}
+
+			if !errors.Is(err, ErrNoCredential) {
+				return err
+			}
+		}
+
+		if !term.IsTerminal(int(syscall.Stdin)) {
+			return ErrNoTerminal
+		}
+
+		secret, err := term.ReadPassword(int(syscall.Stdin))
+
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintln(os.Stderr)
+		_, err = fmt.Fprintln(w, string(secret))
+
+		return err
+	case ActionInput:
+		if !term.IsTerminal(int(syscall.Stdin)) {
+			return ErrNoTerminal
+		}
+
+		line, err := stdinReader.ReadString('\n')
+
+		if err != nil {
+			return err
+		}
+
+		_, err = fmt.Fprintln(w, strings.TrimSpace(line))
+
+		return err
+	case ActionChoose:
+		if r.Option == "" {
+			return PromptRule{Action: ActionInput}.answer(ctx, spec, w, match)
+		}
+
+		fmt.Fprintln(os.Stderr, r.Option)
+		_, err := fmt.Fprintln(w, r.Option)
+
+		return err
+	case ActionNotify:
+		message := r.Message
+
+		if message == "" {
+			message = strings.TrimSpace(match.Text)
+		}
+
+		logger.Info().
+			Str("prompt", r.Name).
+			Msg(message)
+
+		return nil
+	case ActionFail:
+		message := r.Message
+
+		if message == "" {
+			message = strings.TrimSpace(match.Text)
+		}
+
+		return fmt.Errorf("%w: %s", ErrPromptFailed, message)
+	case ActionBrowser:
+		url := urlPattern.FindString(match.Text)
+
+		if url == "" {
+			return fmt.Errorf("no login URL found in output of prompt rule '%s'", r.Name)
+		}
+
+		openBrowser(spec.Browser, url)
+
+		return nil
+	}
+
+	return fmt.Errorf("unknown action '%s' for prompt rule '%s'", r.Action, r.Name)
+}
+
+// openBrowser shows the SSO login URL and runs the browser command with the
+// URL as its last argument. Failing to open a browser is not fatal since the
+// user can still follow the URL
+func openBrowser(browser string, url string) {
+	fmt.Fprintf(os.Stderr, "\n  Complete the SSO login in your browser:\n\n    %s\n\n", url)
+
+	if browser == "" || browser == "none" {
+		return
+	}
+
+	opener := exec.Command("sh", "-c", browser+` "$1"`, "sh", url)
+
+	err := opener.Start()
+
+	if err != nil {
+		logger.Warn().
+			Err(err).
+			Str("browser", browser).
+			Msg("Failed to open browser, open the login URL manually")
+
+		return
+	}
+
+	go func() {
+		_ = opener.Wait()
+	}()
+}
@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"context"
+	"io"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/RiskyFeryansyahP/paycast/internal/store"
+)
+
+func TestPromptRules(t *testing.T) {
+	defaults := make([]string, 0, len(DefaultPromptRules))
+
+	for _, rule := range DefaultPromptRules {
+		defaults = append(defaults, rule.Name)
+	}
+
+	tests := []struct {
+		name      string
+		overrides []store.PromptRule
+		want      []string
+		wantErr   bool
+	}{
+		{
+			name: "no overrides",
+			want: defaults,
+		},
+		{
+			name: "custom rule before the built-ins",
+			overrides: []store.PromptRule{
+				{Name: "banner", Pattern: `(?i)press enter`, Action: "choose"},
+			},
+			want: append([]string{"banner"}, defaults...),
+		},
+		{
+			name: "override replaces the built-in of the same name",
+			overrides: []store.PromptRule{
+				{Name: "device", Pattern: `Choose device:\s*$`, Action: "choose", Option: "2"},
+			},
+			want: append([]string{"device"}, slices.DeleteFunc(slices.Clone(defaults), func(name string) bool {
+				return name == "device"
+			})...),
+		},
+		{
+			name: "invalid pattern",
+			overrides: []store.PromptRule{
+				{Name: "broken", Pattern: `(`, Action: "secret"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "pattern matching empty output",
+			overrides: []store.PromptRule{
+				{Name: "banner", Pattern: `(welcome)?`, Action: "notify"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "unknown action",
+			overrides: []store.PromptRule{
+				{Name: "typo", Pattern: `token:`, Action: "secrets"},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, err := PromptRules(tt.overrides)
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("PromptRules() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			names := make([]string, 0, len(rules))
+
+			for _, rule := range rules {
+				names = append(names, rule.Name)
+			}
+
+			if !slices.Equal(names, tt.want) {
+				t.Errorf("PromptRules() = %v, want %v", names, tt.want)
+			}
+		})
+	}
+}
+
+func TestPromptRulesOverrideFields(t *testing.T) {
+	rules, err := PromptRules([]store.PromptRule{
+		{Name: "device", Pattern: `Choose device:\s*$`, Action: "choose", Option: "2"},
+	})
+
+	if err != nil {
+		t.Fatalf("PromptRules() error = %v", err)
+	}
+
+	rule := rules[0]
+
+	if rule.Action != ActionChoose || rule.Option != "2" || !rule.Pattern.MatchString("Choose device: ") {
+		t.Errorf("PromptRules() override = %+v", rule)
+	}
+}
+
+func TestDefaultPromptRulesSSO(t *testing.T) {
+	const url = "http://127.0.0.1:41235/2a6f1c3e-8d4b-4f0a-9c1e-5b7d2e9f3a10"
+
+	tests := []struct {
+		name   string
+		output string
+	}{
+		{
+			name: "browser opened by tsh",
+			output: "If browser window does not open automatically, open it by clicking on the link:\n" +
+				" " + url + "\n",
+		},
+		{
+			name: "browser none",
+			output: "Use the following URL to authenticate:\n" +
+				" " + url + "\n",
+		},
+	}
+
+	rules := make([]ExpectRule, 0, len(DefaultPromptRules))
+
+	for _, rule := range DefaultPromptRules {
+		rules = append(rules, ExpectRule{Name: rule.Name, Pattern: rule.Pattern})
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, w := io.Pipe()
+			defer w.Close()
+
+			go func() {
+				_, _ = w.Write([]byte(tt.output))
+			}()
+
+			expecter := NewExpecter(r, nil)
+			defer expecter.Close()
+
+			match, err := expecter.Expect(context.Background(), 5*time.Second, rules...)
+
+			if err != nil {
+				t.Fatalf("Expect() error = %v", err)
+			}
+
+			if match.Rule.Name != "sso" {
+				t.Errorf("Expect() rule = %q, want %q", match.Rule.Name, "sso")
+			}
+
+			if got := urlPattern.FindString(match.Text); got != url {
+				t.Errorf("login URL = %q, want %q", got, url)
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/RiskyFeryansyahP/paycast/internal/store"
+	"github.com/RiskyFeryansyahP/paycast/pkg/exit"
+	"github.com/RiskyFeryansyahP/paycast/pkg/logger"
+)
+
+// ErrNotLoggedIn is returned when tsh has no active profile
+var ErrNotLoggedIn = exit.New(exit.NotLoggedIn, errors.New("not logged in to teleport"), "Run 'paycast login' to log in")
+
+// StatusResponse mirrors the document printed by `tsh status --format=json`
+type StatusResponse struct {
+	Active   *ProfileStatus  `json:"active,omitempty"`
+	Profiles []ProfileStatus `json:"profiles"`
+}
+
+// ProfileStatus is a single tsh profile as reported by `tsh status --format=json`
+type ProfileStatus struct {
+	ProxyURL          string              `json:"profile_url"`
+	Username          string              `json:"username"`
+	ActiveRequests    []string            `json:"active_requests,omitempty"`
+	Cluster           string              `json:"cluster"`
+	Roles             []string            `json:"roles,omitempty"`
+	Traits            map[string][]string `json:"traits,omitempty"`
+	Logins            []string            `json:"logins,omitempty"`
+	KubernetesEnabled bool                `json:"kubernetes_enabled"`
+	KubernetesCluster string              `json:"kubernetes_cluster,omitempty"`
+	KubernetesUsers   []string            `json:"kubernetes_users,omitempty"`
+	KubernetesGroups  []string            `json:"kubernetes_groups,omitempty"`
+	Databases         []string            `json:"databases,omitempty"`
+	ValidUntil        time.Time           `json:"valid_until"`
+	Extensions        []string            `json:"extensions,omitempty"`
+}
+
+// Apply copies the profile details into the given context. Access requests
+// that are no longer active are dropped from the context
+func (p ProfileStatus) Apply(configContext *store.Context) {
+	configContext.Profile = p.ProxyURL
+	configContext.Cluster = p.Cluster
+	configContext.Roles = p.Roles
+	configContext.Traits = p.Traits
+	configContext.Logins = p.Logins
+	configContext.ActiveRequests = p.ActiveRequests
+	configContext.KeepRequests(p.ActiveRequests)
+	configContext.Expiry = p.ValidUntil.UTC()
+}
+
+// FetchStatus runs `tsh status --format=json` against the given tsh home and
+// decodes its output
+func FetchStatus(ctx context.Context, home string) (*StatusResponse, error) {
+	var stdout, stderr bytes.Buffer
+
+	cmd := Command(ctx, home, "status", "--format=json")
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+
+	// tsh releases without --format print the human readable status instead
+	if err != nil && formatUnsupported(stderr.String()) {
+		return fetchStatusText(ctx, home)
+	}
+
+	if err != nil && stdout.Len() == 0 {
+		logger.Error().
+			Err(err).
+			Str("stderr", string(bytes.TrimSpace(stderr.Bytes()))).
+			Msg("tsh status command failed")
+
+		return nil, ErrNotLoggedIn
+	}
+
+	var status StatusResponse
+
+	err = json.Unmarshal(stdout.Bytes(), &status)
+
+	if err != nil {
+		logger.Error().
+			Err(err).
+			Msg("Failed to decode tsh status output")
+
+		return nil, err
+	}
+
+	return &status, nil
+}
+
+// formatUnsupported reports whether tsh rejected --format as an unknown flag
+func formatUnsupported(stderr string) bool {
+	return strings.Contains(strings.ToLower(stderr), "unknown long flag '--format'")
+}
+
+// fetchStatusText runs plain `tsh status` and parses its active profile
+func fetchStatusText(ctx context.Context, home string) (*StatusResponse, error) {
+	output, err := Command(ctx, home, "status").CombinedOutput()
+
+	if err != nil {
+		logger.Error().
+			Err(err).
+			Str("output", string(bytes.TrimSpace(output))).
+			Msg("tsh status command failed")
+
+		return nil, ErrNotLoggedIn
+	}
+
+	profile, err := parseStatusText(string(output), time.Now())
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &StatusResponse{Active: profile, Profiles: []ProfileStatus{*profile}}, nil
+}
+
+// parseStatusText reads the first profile block of human readable tsh status
+// or tsh login output
+func parseStatusText(output string, now time.Time) (*ProfileStatus, error) {
+	var profile ProfileStatus
+	found := false
+
+	for _, line := range strings.Split(CleanOutput(output), "\n") {
+		line = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), ">"))
+
+		if line == "" && found {
+			break
+		}
+
+		key, value, ok := strings.Cut(line, ":")
+
+		if !ok {
+			continue
+		}
+
+		value = strings.TrimSpace(value)
+
+		switch strings.TrimSpace(key) {
+		case "Profile URL":
+			profile.ProxyURL = value
+			found = true
+		case "Logged in as":
+			profile.Username = value
+		case "Cluster":
+			profile.Cluster = value
+		case "Roles":
+			profile.Roles = splitList(value)
+		case "Logins":
+			profile.Logins = splitList(value)
+		case "Active requests":
+			profile.ActiveRequests = splitList(value)
+		case "Valid until":
+			expiry, err := ParseValidUntil(value, now)
+
+			if err != nil {
+				return nil, err
+			}
+
+			profile.ValidUntil = expiry
+		}
+	}
+
+	if !found {
+		return nil, ErrNotLoggedIn
+	}
+
+	return &profile, nil
+}
+
+func splitList(value string) []string {
+	var items []string
+
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
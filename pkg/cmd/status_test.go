@@ -0,0 +1,38 @@
+package cmd
+
+import "testing"
+
+func TestFormatUnsupported(t *testing.T) {
+	tests := []struct {
+		name   string
+		stderr string
+		want   bool
+	}{
+		{
+			name:   "unknown flag",
+			stderr: "ERROR: unknown long flag '--format'\n",
+			want:   true,
+		},
+		{
+			name:   "not logged in",
+			stderr: "ERROR: Not logged in.\n",
+		},
+		{
+			name:   "other error mentioning format",
+			stderr: "ERROR: invalid format of profile file\n",
+		},
+		{
+			name: "empty",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := formatUnsupported(tt.stderr)
+
+			if got != tt.want {
+				t.Errorf("formatUnsupported() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,193 +1,190 @@
 package cmd
 
 import (
-	"bufio"
 	"context"
+	"errors"
 	"fmt"
+	"net/url"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
-	"syscall"
 	"time"
 
+	"github.com/RiskyFeryansyahP/paycast/internal/hook"
 	"github.com/RiskyFeryansyahP/paycast/internal/store"
+	"github.com/RiskyFeryansyahP/paycast/pkg/dryrun"
+	"github.com/RiskyFeryansyahP/paycast/pkg/exit"
 	"github.com/RiskyFeryansyahP/paycast/pkg/logger"
-	"github.com/creack/pty"
-	"golang.org/x/term"
 )
 
-func Relogin(ctx context.Context, configContext *store.Context) (*store.Context, error) {
-	cmd := exec.Command("tsh", "login", configContext.Cluster)
-	cmd.Env = append(os.Environ(), "TERM=dumb")
-
-	ptyF, err := pty.Start(cmd)
+// Command prepares a tsh invocation. When home is not empty tsh keeps its
+// profiles there instead of ~/.tsh, so contexts with their own home can be
+// logged in at the same time. In a dry run the command line is logged and
+// the returned command runs true instead, with no output
+func Command(ctx context.Context, home string, args ...string) *exec.Cmd {
+	name := "tsh"
 
-	if err != nil {
-		logger.Error().
-			Err(err).
-			Msg("Failed to start terminal session for tsh login")
+	if dryrun.Enabled() {
+		logger.Info().
+			Str("command", dryrun.CommandLine("tsh", args...)).
+			Str("home", home).
+			Msg("Dry run, not running")
 
-		return nil, err
+		name = "true"
 	}
-	defer ptyF.Close()
 
-	scanner := bufio.NewScanner(ptyF)
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Env = append(os.Environ(), "TERM=dumb")
 
-	for scanner.Scan() {
-		line := scanner.Text()
+	if home != "" {
+		cmd.Env = append(cmd.Env, "TELEPORT_HOME="+home)
+	}
 
-		fmt.Println(line)
+	return cmd
+}
 
-		if strings.Contains(line, "password") {
-			password, err := term.ReadPassword(int(syscall.Stdin))
+// AuthHint is shown when logging in to teleport fails
+const AuthHint = "Check the credentials and connector of the context and log in again"
 
-			if err != nil {
-				logger.Error().
-					Err(err).
-					Msg("Failed to read password input")
+// ErrProfileMismatch is returned when tsh ended up logged in with a different
+// proxy or user than the context asked for
+var ErrProfileMismatch = errors.New("tsh profile does not match the context")
 
-				return nil, err
-			}
+// NewLoginSpec rebuilds the complete tsh login invocation of a context from
+// its stored parameters
+func NewLoginSpec(configContext store.Context) (LoginSpec, error) {
+	prompts, err := PromptRules(configContext.Prompts)
 
-			fmt.Fprintln(ptyF, string(password))
-			continue
-		}
+	if err != nil {
+		return LoginSpec{}, err
+	}
 
-		if strings.Contains(line, "OTP") {
-			otp, err := term.ReadPassword(int(syscall.Stdin))
+	cluster := configContext.URL
 
-			if err != nil {
-				logger.Error().
-					Err(err).
-					Msg("Failed to read OTP input")
+	if cluster == "" {
+		cluster = configContext.Cluster
+	}
 
-				return nil, err
-			}
+	requestIDs := configContext.LoginRequestIDs(time.Now())
+	extraArgs := make([]string, 0, len(requestIDs)+len(configContext.LoginArgs))
 
-			fmt.Fprintln(ptyF, string(otp))
-			continue
-		}
+	for _, requestID := range requestIDs {
+		extraArgs = append(extraArgs, fmt.Sprintf("--request-id=%s", requestID))
+	}
 
-		if !strings.Contains(line, "password") && !strings.Contains(line, "OTP") {
-			if strings.Contains(line, "Profile") {
-				profile := strings.TrimSpace(strings.Split(line, ": ")[1])
-				configContext.Profile = profile
-				continue
-			}
+	extraArgs = append(extraArgs, configContext.LoginArgs...)
+
+	return LoginSpec{
+		Context:          configContext.Name,
+		Home:             configContext.TeleportHome(),
+		Proxy:            configContext.Proxy,
+		Auth:             configContext.Auth,
+		User:             configContext.User,
+		Cluster:          cluster,
+		TTL:              time.Duration(configContext.TTL) * time.Minute,
+		ExtraArgs:        extraArgs,
+		Prompts:          prompts,
+		Browser:          configContext.Browser,
+		CredentialHelper: configContext.CredentialHelper,
+	}, nil
+}
 
-			if strings.Contains(line, "Cluster") {
-				cluster := strings.TrimSpace(strings.Split(line, ": ")[1])
-				configContext.Cluster = cluster
-				continue
-			}
+// Verify checks that the session belongs to the proxy and user of the spec
+func (s *Session) Verify() error {
+	if s.DryRun {
+		return nil
+	}
 
-			if strings.Contains(line, "Valid") {
-				valid := strings.TrimSpace(strings.Split(line, ": ")[1])
-				validTime := strings.Split(valid, " ")[0:2]
-				expiry := strings.Join(validTime, " ")
+	if s.Spec.User != "" && s.Profile.Username != s.Spec.User {
+		return fmt.Errorf("%w: logged in as user '%s', expected '%s'", ErrProfileMismatch, s.Profile.Username, s.Spec.User)
+	}
 
-				expiryTime, err := time.Parse(time.DateTime, expiry)
+	if s.Spec.Proxy == "" {
+		return nil
+	}
 
-				if err != nil {
-					logger.Error().
-						Err(err).
-						Str("expiry", expiry).
-						Msg("Failed to parse session expiry time from tsh output")
+	expected := proxyHost(s.Spec.Proxy)
+	actual := proxyHost(s.Profile.ProxyURL)
 
-					return nil, err
-				}
+	if expected != actual {
+		return fmt.Errorf("%w: logged in to proxy '%s', expected '%s'", ErrProfileMismatch, actual, expected)
+	}
 
-				configContext.Expiry = expiryTime
+	return nil
+}
 
-				continue
-			}
-		}
+// proxyHost extracts the host name of a proxy address or profile URL
+func proxyHost(addr string) string {
+	if !strings.Contains(addr, "://") {
+		addr = "https://" + addr
 	}
 
-	err = cmd.Wait()
+	u, err := url.Parse(addr)
 
 	if err != nil {
-		logger.Error().
-			Err(err).
-			Msg("tsh login command failed. Please check your credentials and try again")
-
-		return nil, err
+		return addr
 	}
 
-	configContext, err = Status(ctx, configContext)
+	return u.Hostname()
+}
 
-	if err != nil {
-		return nil, err
+// ProfilePath returns where tsh keeps the profile of a proxy
+func ProfilePath(home string, proxy string) string {
+	if home == "" {
+		homeDir, _ := os.UserHomeDir()
+		home = filepath.Join(homeDir, ".tsh")
 	}
 
-	return configContext, nil
+	return filepath.Join(home, proxyHost(proxy)+".yaml")
 }
 
-func Status(ctx context.Context, configContext *store.Context) (*store.Context, error) {
-	cmd := exec.Command("tsh", "status")
-	cmd.Env = append(os.Environ(), "TERM=dumb")
+// HasProfile reports whether tsh has a profile for the proxy, without
+// running tsh
+func HasProfile(home string, proxy string) bool {
+	if proxy == "" {
+		return false
+	}
 
-	ptyF, err := pty.Start(cmd)
+	_, err := os.Stat(ProfilePath(home, proxy))
 
-	if err != nil {
-		logger.Error().
-			Err(err).
-			Msg("Failed to start terminal session for tsh login")
+	return err == nil
+}
 
+// Relogin authenticates again with the stored parameters of the context and
+// updates it with the resulting session
+func Relogin(ctx context.Context, configContext *store.Context) (*store.Context, error) {
+	spec, err := NewLoginSpec(*configContext)
+
+	if err != nil {
 		return nil, err
 	}
-	defer ptyF.Close()
-
-	scanner := bufio.NewScanner(ptyF)
-
-	for scanner.Scan() {
-		line := scanner.Text()
 
-		fmt.Println(line)
+	err = hook.Run(ctx, configContext.Hooks, hook.NewEvent(hook.PreLogin, *configContext))
 
-		if !strings.Contains(line, "password") && !strings.Contains(line, "OTP") {
-			if strings.Contains(line, "Profile") {
-				profile := strings.TrimSpace(strings.Split(line, ": ")[1])
-				configContext.Profile = profile
-				continue
-			}
-
-			if strings.Contains(line, "Cluster") {
-				cluster := strings.TrimSpace(strings.Split(line, ": ")[1])
-				configContext.Cluster = cluster
-				continue
-			}
+	if err != nil {
+		return nil, err
+	}
 
-			if strings.Contains(line, "Valid") {
-				valid := strings.TrimSpace(strings.Split(line, ": ")[1])
-				validTime := strings.Split(valid, " ")[0:2]
-				expiry := strings.Join(validTime, " ")
+	session, err := Login(ctx, spec)
 
-				expiryTime, err := time.Parse(time.DateTime, expiry)
+	if err != nil {
+		return nil, exit.Wrap(exit.AuthFailed, err, AuthHint)
+	}
 
-				if err != nil {
-					logger.Error().
-						Err(err).
-						Str("expiry", expiry).
-						Msg("Failed to parse session expiry time from tsh output")
+	err = session.Verify()
 
-					return nil, err
-				}
+	if err != nil {
+		return nil, err
+	}
 
-				configContext.Expiry = expiryTime
+	session.Apply(configContext)
 
-				continue
-			}
-		}
-	}
+	RefreshKube(ctx, *configContext)
 
-	err = cmd.Wait()
+	err = hook.Run(ctx, configContext.Hooks, hook.NewEvent(hook.PostLogin, *configContext))
 
 	if err != nil {
-		logger.Error().
-			Err(err).
-			Msg("tsh login command failed. Please check your credentials and try again")
-
 		return nil, err
 	}
 
@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSessionVerify(t *testing.T) {
+	tests := []struct {
+		name    string
+		session Session
+		wantErr bool
+	}{
+		{
+			name: "matching proxy and user",
+			session: Session{
+				Spec:    LoginSpec{Proxy: "proxy.example.com:443", User: "alice"},
+				Profile: ProfileStatus{ProxyURL: "https://proxy.example.com:443", Username: "alice"},
+			},
+		},
+		{
+			name: "proxy without port",
+			session: Session{
+				Spec:    LoginSpec{Proxy: "proxy.example.com"},
+				Profile: ProfileStatus{ProxyURL: "https://proxy.example.com:3080", Username: "alice"},
+			},
+		},
+		{
+			name: "no proxy or user to check",
+			session: Session{
+				Profile: ProfileStatus{ProxyURL: "https://other.example.com", Username: "bob"},
+			},
+		},
+		{
+			name: "other user",
+			session: Session{
+				Spec:    LoginSpec{Proxy: "proxy.example.com:443", User: "alice"},
+				Profile: ProfileStatus{ProxyURL: "https://proxy.example.com:443", Username: "bob"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "other proxy",
+			session: Session{
+				Spec:    LoginSpec{Proxy: "proxy.example.com:443", User: "alice"},
+				Profile: ProfileStatus{ProxyURL: "https://other.example.com:443", Username: "alice"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "dry run",
+			session: Session{
+				Spec:   LoginSpec{Proxy: "proxy.example.com:443", User: "alice"},
+				DryRun: true,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.session.Verify()
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Verify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr && !errors.Is(err, ErrProfileMismatch) {
+				t.Errorf("Verify() error = %v, want %v", err, ErrProfileMismatch)
+			}
+		})
+	}
+}
+
+func TestProxyHost(t *testing.T) {
+	tests := []struct {
+		name string
+		addr string
+		want string
+	}{
+		{
+			name: "host and port",
+			addr: "proxy.example.com:443",
+			want: "proxy.example.com",
+		},
+		{
+			name: "host only",
+			addr: "proxy.example.com",
+			want: "proxy.example.com",
+		},
+		{
+			name: "profile url",
+			addr: "https://proxy.example.com:3080",
+			want: "proxy.example.com",
+		},
+		{
+			name: "ipv6",
+			addr: "[::1]:3080",
+			want: "::1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := proxyHost(tt.addr)
+
+			if got != tt.want {
+				t.Errorf("proxyHost() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package dryrun
+
+import (
+	"net/url"
+	"strings"
+)
+
+var enabled bool
+
+// secretFlags are the parts of flag names whose values are masked
+var secretFlags = []string{"password", "passwd", "secret", "token", "otp", "passphrase", "private-key"}
+
+// Mask replaces secret values in printed command lines
+const Mask = "****"
+
+// Set turns the dry run mode on or off
+func Set(on bool) {
+	enabled = on
+}
+
+// Enabled reports whether paycast only shows what it would do, without
+// running processes or writing files
+func Enabled() bool {
+	return enabled
+}
+
+// Message returns done, or would in a dry run, so that messages reporting a
+// change do not claim it was made when nothing was written
+func Message(done, would string) string {
+	if enabled {
+		return would
+	}
+
+	return done
+}
+
+// CommandLine renders a command for display with secret flag values and
+// URL passwords masked
+func CommandLine(name string, args ...string) string {
+	masked := make([]string, 0, len(args)+1)
+	masked = append(masked, name)
+
+	maskNext := false
+
+	for _, arg := range args {
+		switch {
+		case maskNext:
+			arg = Mask
+			maskNext = false
+		case strings.HasPrefix(arg, "-"):
+			flag, _, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+
+			if isSecret(flag) {
+				if hasValue {
+					arg = arg[:strings.Index(arg, "=")+1] + Mask
+				} else {
+					maskNext = true
+				}
+			}
+		default:
+			arg = maskURL(arg)
+		}
+
+		masked = append(masked, quote(arg))
+	}
+
+	return strings.Join(masked, " ")
+}
+
+func isSecret(flag string) bool {
+	flag = strings.ToLower(flag)
+
+	for _, secret := range secretFlags {
+		if strings.Contains(flag, secret) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func maskURL(arg string) string {
+	if !strings.Contains(arg, "://") {
+		return arg
+	}
+
+	u, err := url.Parse(arg)
+
+	if err != nil || u.User == nil {
+		return arg
+	}
+
+	if _, ok := u.User.Password(); !ok {
+		return arg
+	}
+
+	// The mask is inserted after encoding, url.UserPassword would escape it
+	u.User = url.User(u.User.Username())
+	prefix := u.Scheme + "://" + u.User.String()
+	masked := u.String()
+
+	if !strings.HasPrefix(masked, prefix) {
+		return masked
+	}
+
+	return prefix + ":" + Mask + masked[len(prefix):]
+}
+
+// quote wraps arguments the shell would split
+func quote(arg string) string {
+	if arg != "" && !strings.ContainsAny(arg, " \t\n\"'$`\\|&;<>()*?") {
+		return arg
+	}
+
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
@@ -0,0 +1,113 @@
+package exit
+
+import (
+	"errors"
+	"os/exec"
+)
+
+// Code is the exit status of a failed command, scripts can tell failures
+// apart by it
+type Code int
+
+const (
+	OK             Code = 0
+	Failure        Code = 1
+	Usage          Code = 2
+	ContextMissing Code = 3
+	NotLoggedIn    Code = 4
+	SessionExpired Code = 5
+	AuthFailed     Code = 6
+	TshNotFound    Code = 7
+	PortInUse      Code = 8
+)
+
+// Error is a command failure carrying its exit code and an optional hint
+// telling the user how to fix it. A Silent failure is not reported, its code
+// is passed on from a process that already told the user what went wrong
+type Error struct {
+	Code   Code
+	Err    error
+	Hint   string
+	Silent bool
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// New returns err as a failure with code
+func New(code Code, err error, hint string) *Error {
+	return &Error{
+		Code: code,
+		Err:  err,
+		Hint: hint,
+	}
+}
+
+// Status returns the exit status of a child process as a silent failure
+func Status(code Code, err error) *Error {
+	return &Error{
+		Code:   code,
+		Err:    err,
+		Silent: true,
+	}
+}
+
+// Wrap attaches code to err unless err already carries a code of its own,
+// so the most specific cause decides the exit status
+func Wrap(code Code, err error, hint string) error {
+	if err == nil {
+		return nil
+	}
+
+	if CodeOf(err) != Failure {
+		return err
+	}
+
+	return New(code, err, hint)
+}
+
+// CodeOf returns the exit code of err, Failure for errors without one
+func CodeOf(err error) Code {
+	if err == nil {
+		return OK
+	}
+
+	var exitErr *Error
+
+	if errors.As(err, &exitErr) {
+		return exitErr.Code
+	}
+
+	if errors.Is(err, exec.ErrNotFound) {
+		return TshNotFound
+	}
+
+	return Failure
+}
+
+// HintOf returns the hint of err, if any
+func HintOf(err error) string {
+	var exitErr *Error
+
+	if errors.As(err, &exitErr) {
+		return exitErr.Hint
+	}
+
+	if errors.Is(err, exec.ErrNotFound) {
+		return "Install tsh and make sure it is on PATH"
+	}
+
+	return ""
+}
+
+// IsSilent reports whether err should not be reported
+func IsSilent(err error) bool {
+	var exitErr *Error
+
+	return errors.As(err, &exitErr) && exitErr.Silent
+}
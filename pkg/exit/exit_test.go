@@ -0,0 +1,153 @@
+package exit
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"testing"
+)
+
+func TestCodeOf(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want Code
+	}{
+		{
+			name: "nil",
+			want: OK,
+		},
+		{
+			name: "plain error",
+			err:  errors.New("boom"),
+			want: Failure,
+		},
+		{
+			name: "exit error",
+			err:  New(SessionExpired, errors.New("expired"), ""),
+			want: SessionExpired,
+		},
+		{
+			name: "wrapped exit error",
+			err:  fmt.Errorf("failed to run: %w", New(PortInUse, errors.New("in use"), "")),
+			want: PortInUse,
+		},
+		{
+			name: "tsh not found",
+			err:  fmt.Errorf("failed to run tsh: %w", &exec.Error{Name: "tsh", Err: exec.ErrNotFound}),
+			want: TshNotFound,
+		},
+		{
+			name: "shell status",
+			err:  Status(42, errors.New("exit status 42")),
+			want: 42,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CodeOf(tt.err)
+
+			if got != tt.want {
+				t.Errorf("CodeOf() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWrap(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		wantNil  bool
+		wantCode Code
+		wantHint string
+	}{
+		{
+			name:    "nil",
+			wantNil: true,
+		},
+		{
+			name:     "plain error takes the code",
+			err:      errors.New("login failed"),
+			wantCode: AuthFailed,
+			wantHint: "Check your credentials",
+		},
+		{
+			name:     "specific code kept",
+			err:      fmt.Errorf("login failed: %w", New(NotLoggedIn, errors.New("not logged in"), "Run 'paycast login'")),
+			wantCode: NotLoggedIn,
+			wantHint: "Run 'paycast login'",
+		},
+		{
+			name:     "tsh not found kept",
+			err:      &exec.Error{Name: "tsh", Err: exec.ErrNotFound},
+			wantCode: TshNotFound,
+			wantHint: "Install tsh and make sure it is on PATH",
+		},
+		{
+			name:     "failure code replaced",
+			err:      New(Failure, errors.New("failed"), "Try again"),
+			wantCode: AuthFailed,
+			wantHint: "Check your credentials",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Wrap(AuthFailed, tt.err, "Check your credentials")
+
+			if tt.wantNil {
+				if err != nil {
+					t.Fatalf("Wrap() = %v, want nil", err)
+				}
+
+				return
+			}
+
+			if code := CodeOf(err); code != tt.wantCode {
+				t.Errorf("CodeOf(Wrap()) = %d, want %d", code, tt.wantCode)
+			}
+
+			if hint := HintOf(err); hint != tt.wantHint {
+				t.Errorf("HintOf(Wrap()) = %q, want %q", hint, tt.wantHint)
+			}
+
+			if !errors.Is(err, tt.err) {
+				t.Errorf("Wrap() = %v, does not wrap %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestIsSilent(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{
+			name: "shell status",
+			err:  fmt.Errorf("shell: %w", Status(1, errors.New("exit status 1"))),
+			want: true,
+		},
+		{
+			name: "exit error",
+			err:  New(Failure, errors.New("failed"), ""),
+		},
+		{
+			name: "plain error",
+			err:  errors.New("failed"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := IsSilent(tt.err)
+
+			if got != tt.want {
+				t.Errorf("IsSilent() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package logger
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"sync"
 	"time"
@@ -11,9 +12,31 @@ import (
 	"github.com/rs/zerolog/pkgerrors"
 )
 
+const (
+	FormatConsole = "console"
+	FormatJSON    = "json"
+)
+
+// Formats lists the log formats, for flag help and completion
+var Formats = []string{FormatConsole, FormatJSON}
+
+// Options configures the logger, the zero value logs info and above to
+// stderr in the console format
+type Options struct {
+	// Level is the minimum level, it takes precedence over Verbose
+	Level string
+	// Verbose lowers the level to debug once and to trace twice
+	Verbose int
+	// Format is one of Formats, console when empty
+	Format string
+	// File also writes the logs to a file, appending to it
+	File string
+}
+
 var (
-	once   sync.Once
+	mu     sync.Mutex
 	logger zerolog.Logger
+	closer io.Closer
 )
 
 func Logger() *zerolog.Logger {
@@ -48,39 +71,122 @@ func Warn() *zerolog.Event {
 	return Logger().Warn()
 }
 
-func init() {
-	once.Do(func() {
-		writer := diode.NewWriter(os.Stdout, 1000, 10*time.Millisecond, func(missed int) {
-			fmt.Printf("drop logs %d", missed)
-		})
+// Configure replaces the logger with one built from opts, flushing the
+// previous one. Without a level, LOG_LEVEL and ENVIRONMENT=local still apply
+func Configure(opts Options) error {
+	level, err := parseLevel(opts)
 
-		env := os.Getenv("ENVIRONMENT")
+	if err != nil {
+		return err
+	}
 
-		logLevel := os.Getenv("LOG_LEVEL")
+	format := opts.Format
 
-		if env == "local" {
-			zerolog.SetGlobalLevel(zerolog.DebugLevel)
-			zerolog.ErrorStackMarshaler = pkgerrors.MarshalStack
-		} else if logLevel != "" {
-			level, err := zerolog.ParseLevel(logLevel)
+	if format == "" {
+		format = FormatConsole
+	}
 
-			if err != nil {
-				zerolog.SetGlobalLevel(zerolog.InfoLevel)
-			} else {
-				zerolog.SetGlobalLevel(level)
-			}
+	if format != FormatConsole && format != FormatJSON {
+		return fmt.Errorf("unknown log format '%s', use one of console or json", format)
+	}
 
-		} else {
-			zerolog.SetGlobalLevel(zerolog.InfoLevel)
+	// Closing the logger must flush it without closing stderr
+	var out io.Writer = struct{ io.Writer }{os.Stderr}
+
+	if format == FormatConsole {
+		out = zerolog.ConsoleWriter{Out: out}
+	}
+
+	if opts.File != "" {
+		file, err := os.OpenFile(opts.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+
+		if err != nil {
+			return fmt.Errorf("failed to open log file: %w", err)
 		}
 
-		output := zerolog.ConsoleWriter{Out: os.Stdout}
+		var fileOut io.Writer = file
 
-		logger = zerolog.New(writer).
-			Output(output).
-			With().
-			Timestamp().
-			Logger()
+		if format == FormatConsole {
+			fileOut = zerolog.ConsoleWriter{Out: file, NoColor: true}
+		}
 
+		out = zerolog.MultiLevelWriter(out, fileOut)
+	}
+
+	writer := diode.NewWriter(out, 1000, 10*time.Millisecond, func(missed int) {
+		fmt.Fprintf(os.Stderr, "drop logs %d\n", missed)
 	})
+
+	zerolog.SetGlobalLevel(level)
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if closer != nil {
+		closer.Close()
+	}
+
+	// Fatal closes the writer of the logger, which flushes the diode before
+	// the process exits
+	logger = zerolog.New(writer).
+		With().
+		Timestamp().
+		Logger()
+	closer = writer
+
+	return nil
+}
+
+// Close flushes the buffered logs and closes the log file. It is called
+// before the process exits, logs written afterwards are dropped
+func Close() {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if closer == nil {
+		return
+	}
+
+	closer.Close()
+	closer = nil
+}
+
+func parseLevel(opts Options) (zerolog.Level, error) {
+	if opts.Level != "" {
+		level, err := zerolog.ParseLevel(opts.Level)
+
+		if err != nil {
+			return zerolog.InfoLevel, fmt.Errorf("unknown log level '%s'", opts.Level)
+		}
+
+		return level, nil
+	}
+
+	switch {
+	case opts.Verbose >= 2:
+		return zerolog.TraceLevel, nil
+	case opts.Verbose == 1:
+		return zerolog.DebugLevel, nil
+	}
+
+	if os.Getenv("ENVIRONMENT") == "local" {
+		zerolog.ErrorStackMarshaler = pkgerrors.MarshalStack
+
+		return zerolog.DebugLevel, nil
+	}
+
+	if logLevel := os.Getenv("LOG_LEVEL"); logLevel != "" {
+		level, err := zerolog.ParseLevel(logLevel)
+
+		if err == nil {
+			return level, nil
+		}
+	}
+
+	return zerolog.InfoLevel, nil
+}
+
+func init() {
+	// The defaults log until the root command applies its flags
+	_ = Configure(Options{})
 }
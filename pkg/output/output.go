@@ -0,0 +1,143 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Format selects how command results are written to stdout
+type Format string
+
+const (
+	Text  Format = "text"
+	JSON  Format = "json"
+	YAML  Format = "yaml"
+	Table Format = "table"
+)
+
+// Formats lists the accepted values of the -o flag
+var Formats = []string{string(Text), string(JSON), string(YAML), string(Table)}
+
+var current = Text
+
+// Result is the outcome of a command. Its JSON tags are the stable field
+// names of the json and yaml formats, Header and Rows render it as a table
+type Result interface {
+	Header() []string
+	Rows() [][]string
+}
+
+// Texter is implemented by results with their own text format. Results of
+// commands whose text output is their log line return an empty string, other
+// results are printed as a table
+type Texter interface {
+	Text() string
+}
+
+// Parse validates a format given with -o
+func Parse(value string) (Format, error) {
+	for _, format := range Formats {
+		if value == format {
+			return Format(value), nil
+		}
+	}
+
+	return "", fmt.Errorf("unknown output format '%s', use one of %s", value, strings.Join(Formats, ", "))
+}
+
+// Set selects the format of every following Print
+func Set(format Format) {
+	current = format
+}
+
+// Current returns the selected format
+func Current() Format {
+	return current
+}
+
+// Structured reports whether results are written in another format than
+// text, human logs then belong on stderr to keep stdout parseable
+func Structured() bool {
+	return current != Text
+}
+
+// Print writes the result to stdout in the selected format
+func Print(result Result) error {
+	return Write(os.Stdout, current, result)
+}
+
+// Write writes the result to w in format
+func Write(w io.Writer, format Format, result Result) error {
+	switch format {
+	case JSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+
+		return encoder.Encode(result)
+	case YAML:
+		return writeYAML(w, result)
+	case Table:
+		return writeTable(w, result)
+	}
+
+	if texter, ok := result.(Texter); ok {
+		text := texter.Text()
+
+		if text == "" {
+			return nil
+		}
+
+		_, err := fmt.Fprintln(w, text)
+
+		return err
+	}
+
+	return writeTable(w, result)
+}
+
+// writeYAML goes through JSON so both formats share the field names of the
+// json tags
+func writeYAML(w io.Writer, result Result) error {
+	data, err := json.Marshal(result)
+
+	if err != nil {
+		return err
+	}
+
+	var value any
+
+	err = json.Unmarshal(data, &value)
+
+	if err != nil {
+		return err
+	}
+
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+
+	err = encoder.Encode(value)
+
+	if err != nil {
+		return err
+	}
+
+	return encoder.Close()
+}
+
+func writeTable(w io.Writer, result Result) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(tw, strings.Join(result.Header(), "\t"))
+
+	for _, row := range result.Rows() {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+
+	return tw.Flush()
+}